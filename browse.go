@@ -0,0 +1,521 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+	"github.com/browserutils/kooky"
+)
+
+const browseHelp = "↑↓ move  / search  space mark  a mark all  c copy  e export  q quit"
+
+// browseMode is what the keys of browseView do: move in the list or edit the search or export file name
+type browseMode int
+
+const (
+	browseList browseMode = iota
+	browseSearch
+	browseExport
+)
+
+// browseKey is a key press, name is set for the special keys and r for characters
+type browseKey struct {
+	name string
+	r    rune
+}
+
+// browseEscapeKeys are the escape sequences of the special keys, in the cursor key modes of the common terminals
+var browseEscapeKeys = map[string]string{
+	"\x1b[A": "up", "\x1bOA": "up",
+	"\x1b[B": "down", "\x1bOB": "down",
+	"\x1b[5~": "pgup", "\x1b[6~": "pgdown",
+	"\x1b[H": "home", "\x1bOH": "home", "\x1b[1~": "home", "\x1b[7~": "home",
+	"\x1b[F": "end", "\x1bOF": "end", "\x1b[4~": "end", "\x1b[8~": "end",
+}
+
+// browseView is the state of the browse command: the full-screen list of the cookies
+// matching the search, with the details of the cookie under the cursor in a side pane
+type browseView struct {
+	cookies []*kooky.Cookie
+	shown   []*kooky.Cookie
+	marked  map[*kooky.Cookie]bool
+	cursor  int
+	// offset is the index of the first shown cookie on the screen
+	offset  int
+	query   string
+	mode    browseMode
+	input   string
+	message string
+	// out receives the OSC 52 sequence of copied values
+	out io.Writer
+}
+
+// browseCookies shows the cookies in a full-screen list on the terminal, with fuzzy search over
+// name and domain, the details of the selected cookie, copying values and exporting a selection
+func browseCookies(cookies []*kooky.Cookie) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return errors.New("the browse command needs a terminal")
+	}
+
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer restore()
+	// the alternate screen keeps the scrollback of the terminal as it was
+	fmt.Fprint(os.Stderr, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(os.Stderr, "\x1b[?25h\x1b[?1049l")
+
+	resized := make(chan os.Signal, 1)
+	defer notifyResize(resized)()
+
+	input := make(chan []byte)
+	go func() {
+		defer close(input)
+		for {
+			buffer := make([]byte, 256)
+			n, err := os.Stdin.Read(buffer)
+			if n > 0 {
+				input <- buffer[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	view := newBrowseView(cookies, os.Stderr)
+	for {
+		width, height := terminalSize(os.Stderr)
+		fmt.Fprint(os.Stderr, view.render(width, height))
+
+		select {
+		case data, ok := <-input:
+			if !ok {
+				return nil
+			}
+			for _, key := range parseBrowseKeys(data) {
+				if view.handleKey(key, height) {
+					return nil
+				}
+			}
+		case <-resized:
+		}
+	}
+}
+
+func newBrowseView(cookies []*kooky.Cookie, out io.Writer) *browseView {
+	cookies = sortedCookies(cookies)
+	return &browseView{cookies: cookies, shown: cookies, marked: make(map[*kooky.Cookie]bool), out: out}
+}
+
+// parseBrowseKeys splits the bytes read from the terminal into key presses
+func parseBrowseKeys(data []byte) []browseKey {
+	var keys []browseKey
+	for len(data) > 0 {
+		if data[0] == 0x1b {
+			name, n := parseEscapeKey(data)
+			if n == 0 {
+				keys = append(keys, browseKey{name: "esc"})
+				n = 1
+			} else if name != "" {
+				keys = append(keys, browseKey{name: name})
+			}
+			data = data[n:]
+			continue
+		}
+
+		switch data[0] {
+		case '\r', '\n':
+			keys = append(keys, browseKey{name: "enter"})
+		case 0x7f, 0x08:
+			keys = append(keys, browseKey{name: "backspace"})
+		case 0x03:
+			keys = append(keys, browseKey{name: "ctrl-c"})
+		default:
+			r, size := utf8.DecodeRune(data)
+			if r >= ' ' && r != utf8.RuneError {
+				keys = append(keys, browseKey{r: r})
+			}
+			data = data[size:]
+			continue
+		}
+		data = data[1:]
+	}
+	return keys
+}
+
+// parseEscapeKey returns the key of the escape sequence at the start of data and its length.
+// Unknown control sequences are skipped with an empty name, a lone escape has length 0.
+func parseEscapeKey(data []byte) (string, int) {
+	for sequence, name := range browseEscapeKeys {
+		if strings.HasPrefix(string(data), sequence) {
+			return name, len(sequence)
+		}
+	}
+	if len(data) < 2 || (data[1] != '[' && data[1] != 'O') {
+		return "", 0
+	}
+	// a control sequence ends with a byte in 0x40-0x7e
+	for i := 2; i < len(data); i++ {
+		if data[i] >= 0x40 && data[i] <= 0x7e {
+			return "", i + 1
+		}
+	}
+	return "", len(data)
+}
+
+// handleKey applies a key press, height is that of the screen for paging. It reports whether to quit.
+func (b *browseView) handleKey(key browseKey, height int) bool {
+	b.message = ""
+	if key.name == "ctrl-c" {
+		return true
+	}
+
+	switch b.mode {
+	case browseSearch:
+		switch {
+		case key.name == "enter":
+			b.mode = browseList
+		case key.name == "esc":
+			b.mode = browseList
+			b.setQuery("")
+		case key.name == "backspace":
+			b.setQuery(trimLastRune(b.query))
+		case key.name == "up", key.name == "down":
+			b.mode = browseList
+			return b.handleKey(key, height)
+		case key.r != 0:
+			b.setQuery(b.query + string(key.r))
+		}
+		return false
+
+	case browseExport:
+		switch {
+		case key.name == "enter":
+			b.mode = browseList
+			b.export(strings.TrimSpace(b.input))
+		case key.name == "esc":
+			b.mode = browseList
+		case key.name == "backspace":
+			b.input = trimLastRune(b.input)
+		case key.r != 0:
+			b.input += string(key.r)
+		}
+		return false
+	}
+
+	page := max(browseListHeight(height)-1, 1)
+	switch {
+	case key.name == "up" || key.r == 'k':
+		b.moveCursor(-1)
+	case key.name == "down" || key.r == 'j':
+		b.moveCursor(1)
+	case key.name == "pgup":
+		b.moveCursor(-page)
+	case key.name == "pgdown":
+		b.moveCursor(page)
+	case key.name == "home" || key.r == 'g':
+		b.moveCursor(-len(b.shown))
+	case key.name == "end" || key.r == 'G':
+		b.moveCursor(len(b.shown))
+	case key.r == '/':
+		b.mode = browseSearch
+	case key.name == "esc" && b.query != "":
+		b.setQuery("")
+	case key.r == ' ':
+		if cookie := b.current(); cookie != nil {
+			b.marked[cookie] = !b.marked[cookie]
+			if !b.marked[cookie] {
+				delete(b.marked, cookie)
+			}
+			b.moveCursor(1)
+		}
+	case key.r == 'a':
+		b.toggleMarkAll()
+	case key.r == 'c':
+		b.copyValue()
+	case key.r == 'e':
+		if len(b.exportedCookies()) == 0 {
+			b.message = "no cookies to export"
+		} else {
+			b.mode = browseExport
+			b.input = ""
+		}
+	case key.r == 'q':
+		return true
+	}
+	return false
+}
+
+func trimLastRune(s string) string {
+	_, size := utf8.DecodeLastRuneInString(s)
+	return s[:len(s)-size]
+}
+
+func (b *browseView) setQuery(query string) {
+	b.query = query
+	b.shown = fuzzyFilterCookies(b.cookies, query)
+	b.cursor, b.offset = 0, 0
+}
+
+func (b *browseView) moveCursor(delta int) {
+	b.cursor = min(max(b.cursor+delta, 0), max(len(b.shown)-1, 0))
+}
+
+func (b *browseView) current() *kooky.Cookie {
+	if len(b.shown) == 0 {
+		return nil
+	}
+	return b.shown[b.cursor]
+}
+
+// toggleMarkAll marks all shown cookies, or unmarks them if they are all marked already
+func (b *browseView) toggleMarkAll() {
+	allMarked := true
+	for _, cookie := range b.shown {
+		allMarked = allMarked && b.marked[cookie]
+	}
+	for _, cookie := range b.shown {
+		if allMarked {
+			delete(b.marked, cookie)
+		} else {
+			b.marked[cookie] = true
+		}
+	}
+}
+
+func (b *browseView) copyValue() {
+	cookie := b.current()
+	if cookie == nil {
+		return
+	}
+	// OSC 52 asks the terminal itself to set the clipboard, which also works over ssh
+	fmt.Fprintf(b.out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(cookie.Value)))
+	b.message = "copied the value of " + cookie.Name
+}
+
+// exportedCookies are the marked cookies, or the shown ones if none is marked
+func (b *browseView) exportedCookies() []*kooky.Cookie {
+	if len(b.marked) == 0 {
+		return b.shown
+	}
+	var marked []*kooky.Cookie
+	for _, cookie := range b.cookies {
+		if b.marked[cookie] {
+			marked = append(marked, cookie)
+		}
+	}
+	return marked
+}
+
+func (b *browseView) export(filename string) {
+	if filename == "" {
+		b.message = "missing file to export to"
+		return
+	}
+	exported := b.exportedCookies()
+	if err := exportListedCookies(filename, exported); err != nil {
+		b.message = err.Error()
+		return
+	}
+	b.message = fmt.Sprintf("exported %d cookies to %s", len(exported), filename)
+}
+
+// browseListHeight is the number of list rows, the first row of the screen is the header and the last the status line
+func browseListHeight(height int) int {
+	return max(height-2, 1)
+}
+
+// render draws the whole screen, every row is positioned and cleared, so no previous frame shows through
+func (b *browseView) render(width int, height int) string {
+	listHeight := browseListHeight(height)
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+listHeight {
+		b.offset = b.cursor - listHeight + 1
+	}
+
+	// the side pane needs room, narrow terminals only show the list
+	listWidth, detailsWidth := width, 0
+	if width >= 60 {
+		listWidth = width * 2 / 5
+		detailsWidth = width - listWidth - 3
+	}
+
+	var details []string
+	if cookie := b.current(); cookie != nil && detailsWidth > 0 {
+		details = browseDetails(cookie, detailsWidth)
+		if len(details) > listHeight {
+			details = append(details[:listHeight-1], "… (c copies the whole value)")
+		}
+	}
+
+	var screen strings.Builder
+	header := fmt.Sprintf(" %d of %d cookies", len(b.shown), len(b.cookies))
+	if len(b.marked) > 0 {
+		header += fmt.Sprintf(", %d marked", len(b.marked))
+	}
+	if b.query != "" {
+		header += ", search: " + b.query
+	}
+	fmt.Fprintf(&screen, "\x1b[1;1H\x1b[7m%s\x1b[0m", fitText(header, width))
+
+	nameWidth := max(listWidth/2, 1)
+	for row := 0; row < listHeight; row++ {
+		fmt.Fprintf(&screen, "\x1b[%d;1H", row+2)
+
+		line := ""
+		i := b.offset + row
+		if i < len(b.shown) {
+			cookie := b.shown[i]
+			mark := "  "
+			if b.marked[cookie] {
+				mark = "* "
+			}
+			line = mark + fitText(cookie.Name, nameWidth) + " " + cookie.Domain
+		} else if i == 0 {
+			line = "  no cookies match"
+		}
+		line = fitText(line, listWidth)
+		if i == b.cursor && i < len(b.shown) {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		screen.WriteString(line)
+
+		if detailsWidth > 0 {
+			detail := ""
+			if row < len(details) {
+				detail = details[row]
+			}
+			screen.WriteString(" │ " + fitText(detail, detailsWidth))
+		}
+		screen.WriteString("\x1b[K")
+	}
+
+	status := browseHelp
+	switch {
+	case b.message != "":
+		status = b.message
+	case b.mode == browseSearch:
+		status = "/" + b.query
+	case b.mode == browseExport:
+		status = "export to cookies.txt file: " + b.input
+	}
+	fmt.Fprintf(&screen, "\x1b[%d;1H%s\x1b[K", height, fitText(status, width))
+	return screen.String()
+}
+
+// browseDetails are the lines of the side pane, the value is wrapped over as many lines as it needs
+func browseDetails(cookie *kooky.Cookie, width int) []string {
+	expires := "session"
+	if !cookielib.IsSession(cookie) {
+		expires = cookie.Expires.Format(time.RFC3339) + " (" + describeExpiry(cookie) + ")"
+	}
+	lines := []string{
+		"Name      " + cookie.Name,
+		"Domain    " + cookie.Domain + "  Path " + cookie.Path,
+		"Expires   " + expires,
+		fmt.Sprintf("Flags     Secure %t  HttpOnly %t  SameSite %s", cookie.Secure, cookie.HttpOnly, sameSiteName(cookie.SameSite)),
+	}
+	if source, ok := cookieSources[cookie]; ok {
+		lines = append(lines, "Browser   "+source.Browser+"  Profile "+source.Profile, "Store     "+source.FilePath)
+	}
+	if cookie.Container != "" {
+		lines = append(lines, "Container "+cookie.Container)
+	}
+
+	lines = append(lines, "", fmt.Sprintf("Value (%d bytes)", len(cookie.Value)))
+	value := []rune(printableText(cookie.Value))
+	for len(value) > 0 {
+		n := min(width, len(value))
+		lines = append(lines, string(value[:n]))
+		value = value[n:]
+	}
+	return lines
+}
+
+func sameSiteName(sameSite http.SameSite) string {
+	switch sameSite {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return "-"
+}
+
+// printableText replaces control characters, a cookie value must not be able to send escape sequences to the terminal
+func printableText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || (r >= 0x7f && r < 0xa0) {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// fitText makes the printable text exactly width characters long, cut with … or padded with spaces
+func fitText(s string, width int) string {
+	runes := []rune(printableText(s))
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
+
+// fuzzyFilterCookies keeps the cookies whose name or domain contains the characters of query
+// in order, ignoring case, e.g. "sid" matches "session_id"
+func fuzzyFilterCookies(cookies []*kooky.Cookie, query string) []*kooky.Cookie {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return cookies
+	}
+
+	var matched []*kooky.Cookie
+	for _, cookie := range cookies {
+		if fuzzyMatch(cookie.Name, query) || fuzzyMatch(cookie.Domain, query) {
+			matched = append(matched, cookie)
+		}
+	}
+	return matched
+}
+
+func fuzzyMatch(text string, query string) bool {
+	remaining := []rune(strings.ToLower(query))
+	for _, r := range strings.ToLower(text) {
+		if len(remaining) == 0 {
+			break
+		}
+		if r == remaining[0] {
+			remaining = remaining[1:]
+		}
+	}
+	return len(remaining) == 0
+}
+
+func exportListedCookies(filename string, cookies []*kooky.Cookie) error {
+	var exported strings.Builder
+	if err := cookielib.Export(&exported, sourcedCookies(cookies), cookielib.FormatNetscape); err != nil {
+		return fmt.Errorf("failed to export cookies: %w", err)
+	}
+
+	if err := replaceFile(filename, exported.String()); err != nil {
+		return fmt.Errorf("failed to export cookies to %s: %w", filename, err)
+	}
+	return nil
+}
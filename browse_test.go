@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/browserutils/kooky"
+)
+
+func browseTestCookies(n int) []*kooky.Cookie {
+	cookies := make([]*kooky.Cookie, n)
+	for i := range cookies {
+		cookies[i] = &kooky.Cookie{Cookie: http.Cookie{Name: fmt.Sprintf("cookie%02d", i), Value: "v", Domain: ".example.com", Path: "/"}}
+	}
+	return cookies
+}
+
+func TestParseBrowseKeys(t *testing.T) {
+	keys := parseBrowseKeys([]byte("j\x1b[A\x1b[6~\x1bOB/ü\r\x7f\x1b\x1b[1;5C\x03"))
+	want := []browseKey{{r: 'j'}, {name: "up"}, {name: "pgdown"}, {name: "down"}, {r: '/'}, {r: 'ü'}, {name: "enter"}, {name: "backspace"}, {name: "esc"}, {name: "ctrl-c"}}
+	if len(keys) != len(want) {
+		t.Fatalf("got %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("key %d: got %+v, want %+v", i, keys[i], want[i])
+		}
+	}
+}
+
+func TestBrowseViewScrollsToTheCursor(t *testing.T) {
+	resetFlags(t)
+	view := newBrowseView(browseTestCookies(30), io.Discard)
+
+	view.handleKey(browseKey{name: "end"}, 12)
+	screen := view.render(100, 12)
+	if view.cursor != 29 || view.offset != 20 {
+		t.Errorf("cursor %d and offset %d, want 29 and 20", view.cursor, view.offset)
+	}
+	if !strings.Contains(screen, "\x1b[7m  cookie29") {
+		t.Error("the last cookie isn't highlighted")
+	}
+
+	view.handleKey(browseKey{name: "pgup"}, 12)
+	view.render(100, 12)
+	if view.cursor != 20 || view.offset != 20 {
+		t.Errorf("cursor %d and offset %d after pgup, want 20 and 20", view.cursor, view.offset)
+	}
+}
+
+func TestBrowseViewSearchAndMark(t *testing.T) {
+	resetFlags(t)
+	view := newBrowseView(browseTestCookies(30), io.Discard)
+
+	for _, key := range parseBrowseKeys([]byte("/c1\r")) {
+		view.handleKey(key, 24)
+	}
+	// fuzzy, so cookie01 and cookie21 match as well
+	if len(view.shown) != 12 || view.mode != browseList {
+		t.Fatalf("got %d cookies in mode %d, want 12 in the list", len(view.shown), view.mode)
+	}
+
+	view.handleKey(browseKey{r: 'a'}, 24)
+	view.handleKey(browseKey{name: "esc"}, 24)
+	if len(view.shown) != 30 || len(view.exportedCookies()) != 12 {
+		t.Errorf("got %d shown and %d exported cookies, want 30 and the 12 marked", len(view.shown), len(view.exportedCookies()))
+	}
+}
+
+func TestBrowseViewEscapesValues(t *testing.T) {
+	resetFlags(t)
+	cookies := browseTestCookies(1)
+	cookies[0].Value = "\x1b]52;c;aGk=\a\x1b[2J"
+	screen := newBrowseView(cookies, io.Discard).render(100, 24)
+
+	if strings.Contains(screen, "\x1b]") || strings.Contains(screen, "\x1b[2J") || strings.Contains(screen, "\a") {
+		t.Errorf("the value reached the terminal unescaped: %q", screen)
+	}
+}
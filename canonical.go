@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// canonicalizeJson rewrites JSON into the canonical form of RFC 8785 (JCS):
+// no insignificant whitespace, object keys sorted by their UTF-16 code units,
+// minimal string escaping and ECMAScript number formatting
+func canonicalizeJson(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := writeCanonical(&b, v); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func writeCanonical(b *bytes.Buffer, v interface{}) error {
+	switch value := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(value))
+	case json.Number:
+		f, err := value.Float64()
+		if err != nil {
+			return fmt.Errorf("number %s can't be canonicalized: %w", value, err)
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return fmt.Errorf("number %s can't be canonicalized", value)
+		}
+		b.WriteString(formatCanonicalNumber(f))
+	case string:
+		writeCanonicalString(b, value)
+	case []interface{}:
+		b.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeCanonical(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+
+		b.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonicalString(b, key)
+			b.WriteByte(':')
+			if err := writeCanonical(b, value[key]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected JSON value of type %T", v)
+	}
+
+	return nil
+}
+
+// formatCanonicalNumber formats like ECMAScript's Number.prototype.toString
+func formatCanonicalNumber(f float64) string {
+	if f == 0 {
+		// also turns -0 into 0
+		return "0"
+	}
+
+	abs := math.Abs(f)
+	if abs >= 1e21 || abs < 1e-6 {
+		mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+		sign, digits := exponent[:1], strings.TrimLeft(exponent[1:], "0")
+		return mantissa + "e" + sign + digits
+	}
+
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func writeCanonicalString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// copyToClipboard passes value on stdin to the first clipboard tool of the platform that is installed
+func copyToClipboard(value string) error {
+	var tried []string
+	for _, command := range clipboardCommands() {
+		tool, err := exec.LookPath(command[0])
+		if err != nil {
+			tried = append(tried, command[0])
+			continue
+		}
+
+		var stderr bytes.Buffer
+		cmd := exec.Command(tool, command[1:]...)
+		cmd.Stdin = strings.NewReader(value)
+		cmd.Stderr = &stderr
+		// xclip and wl-copy fork a process that serves the clipboard and keeps the inherited
+		// stderr open, Wait would never see the end of it. Errors are written before the tool exits.
+		cmd.WaitDelay = 200 * time.Millisecond
+		if err := cmd.Run(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+			return fmt.Errorf("%s failed: %w: %s", command[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+
+	return errors.New("no clipboard tool found, install one of " + strings.Join(tried, ", "))
+}
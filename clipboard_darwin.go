@@ -0,0 +1,5 @@
+package main
+
+func clipboardCommands() [][]string {
+	return [][]string{{"pbcopy"}}
+}
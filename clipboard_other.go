@@ -0,0 +1,14 @@
+//go:build !darwin && !windows
+
+package main
+
+import "os"
+
+// clipboardCommands prefers the Wayland tool in a Wayland session, X11 tools still work there through XWayland
+func clipboardCommands() [][]string {
+	x11 := [][]string{{"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return append([][]string{{"wl-copy"}}, x11...)
+	}
+	return x11
+}
@@ -0,0 +1,6 @@
+package main
+
+func clipboardCommands() [][]string {
+	// clip.exe would convert the value to UTF-16 with the console code page
+	return [][]string{{"powershell.exe", "-NoProfile", "-Command", "$input | Set-Clipboard"}, {"clip.exe"}}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// command is a subcommand with its own flags, the flags of the invocation without a command are in addFlatFlags
+type command struct {
+	name        string
+	aliases     []string
+	description string
+	flags       func(fs *pflag.FlagSet)
+	// apply selects the output mode of the command after the flags are parsed
+	apply func()
+}
+
+var commands = []command{
+	{
+		name:        "get",
+		aliases:     []string{"list"},
+		description: "Prints the cookies as JSON, or the value of single cookies with --name",
+		flags: func(fs *pflag.FlagSet) {
+			addStoreFlags(fs)
+			addFilterFlags(fs)
+			addGetFlags(fs)
+			addValueFlags(fs)
+			addOutputFlag(fs)
+		},
+	},
+	{
+		name:        "export",
+		description: "Exports the cookies as cookies.txt (netscape), CSV, JSON lines, Playwright storageState, HAR or JSON file",
+		flags: func(fs *pflag.FlagSet) {
+			addStoreFlags(fs)
+			addFilterFlags(fs)
+			addFormatFlag(fs, "netscape")
+			addValueFlags(fs)
+			fs.BoolVarP(&pretty, "pretty", "p", false, "indents the JSON output with two spaces")
+			fs.BoolVar(&canonical, "canonical", false, "outputs canonical JSON (RFC 8785), e.g. for hashing or signing")
+			addOutputFlag(fs)
+		},
+	},
+	{
+		name:        "curl",
+		description: "Prints a curl command sending the cookies, or with --command a wget, httpie or PowerShell command",
+		flags: func(fs *pflag.FlagSet) {
+			addStoreFlags(fs)
+			addFilterFlags(fs)
+			fs.BoolVar(&wget, "wget", false, "prints a wget command instead of a curl command")
+			addSchemeFlag(fs)
+			addValueFlags(fs)
+			addOutputFlag(fs)
+		},
+		apply: func() {
+			curl = !wget && requestCommand == ""
+		},
+	},
+	{
+		name:        "browse",
+		description: "Browses the cookies in a full-screen list with fuzzy search, details, copying and export",
+		flags: func(fs *pflag.FlagSet) {
+			addStoreFlags(fs)
+			addFilterFlags(fs)
+		},
+		apply: func() {
+			browse = true
+		},
+	},
+	{
+		name:        "diff",
+		description: "Compares the cookies with those of another browser, profile or --full file, e.g. -b chrome --against firefox",
+		flags: func(fs *pflag.FlagSet) {
+			addStoreFlags(fs)
+			addFilterFlags(fs)
+			fs.StringVar(&diffAgainst, "against", "", "browser or JSON file written by --full to compare with")
+			completeWith(fs, "against", "browsers")
+			fs.StringArrayVar(&againstProfiles, "against-profile", nil, "only reads the given profiles of the --against browser, like --profile. Repeatable")
+			completeWith(fs, "against-profile", "profiles")
+			addValueFlags(fs)
+			fs.BoolVarP(&pretty, "pretty", "p", false, "indents the JSON output with two spaces")
+			addOutputFlag(fs)
+		},
+		apply: func() {
+			diffing = true
+		},
+	},
+	{
+		name:        "snapshot",
+		description: "Saves all cookies (or those matching the filters) as --full JSON, optionally encrypted, e.g. snapshot --encrypt --out snapshot.json.enc",
+		flags: func(fs *pflag.FlagSet) {
+			addStoreFlags(fs)
+			addFilterFlags(fs)
+			fs.BoolVar(&encryptSnapshot, "encrypt", false, "encrypts the snapshot with AES-256-GCM and a key derived from the passphrase of --passphrase-file or $COOKIES_PASSPHRASE")
+			addPassphraseFlag(fs)
+			addOutputFlag(fs)
+		},
+		apply: func() {
+			snapshotting = true
+			fullCookieInfo = true
+		},
+	},
+	{
+		name:        "restore",
+		description: "Writes the cookies of a snapshot as cookies.txt or another export format: restore snapshot.json.enc --out cookies.txt",
+		flags: func(fs *pflag.FlagSet) {
+			addFilterFlags(fs)
+			addFormatFlag(fs, "netscape")
+			addPassphraseFlag(fs)
+			addOutputFlag(fs)
+		},
+		apply: func() {
+			restoring = true
+			if flagSet.NArg() > 0 {
+				fromJson = flagSet.Arg(0)
+			}
+		},
+	},
+	{
+		name:        "serve",
+		description: "Serves the cookies as JSON over HTTP, e.g. GET /cookies?domain=example.com&browser=firefox",
+		flags: func(fs *pflag.FlagSet) {
+			fs.StringVar(&serveAddress, "listen", "127.0.0.1:8377", "address the HTTP server listens on")
+			addStoreFlags(fs)
+		},
+	},
+	{
+		name:        "completion",
+		description: "Prints the completion script of a shell: cookie completion bash|zsh|fish|powershell",
+		flags: func(fs *pflag.FlagSet) {
+			addOutputFlag(fs)
+		},
+		apply: func() {
+			completionShell = flagSet.Arg(0)
+			printingCompletion = true
+		},
+	},
+	{
+		name:        "stores",
+		description: "Lists the discovered cookie stores with their browser, profile and path as JSON or table",
+		flags: func(fs *pflag.FlagSet) {
+			fs.BoolVar(&diagnosePerms, "permissions", false, "checks whether every store file can be read instead")
+			fs.BoolVar(&storesTable, "table", false, "prints a table instead of JSON")
+			fs.BoolVar(&localTime, "local-time", false, "shows the modification times in the local timezone instead of UTC")
+			fs.BoolVarP(&pretty, "pretty", "p", false, "indents the JSON output with two spaces")
+			addOutputFlag(fs)
+		},
+		apply: func() {
+			listStoresJson = !diagnosePerms
+		},
+	},
+}
+
+// names are the name and the aliases of the command, e.g. "get, list" in the usage
+func (c command) names() string {
+	return strings.Join(append([]string{c.name}, c.aliases...), ", ")
+}
+
+func addPassphraseFlag(fs *pflag.FlagSet) {
+	fs.StringVar(&passphraseFile, "passphrase-file", "", "reads the passphrase of encrypted snapshots from the file, defaults to $COOKIES_PASSPHRASE")
+}
+
+// findCommand returns the command named by the first argument, nil for the invocation with flags only
+func findCommand(args []string) *command {
+	if len(args) == 0 {
+		return nil
+	}
+	for i := range commands {
+		if commands[i].name == args[0] || slices.Contains(commands[i].aliases, args[0]) {
+			return &commands[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+	"github.com/spf13/pflag"
+)
+
+// completionAnnotation is the flag annotation naming the completer of the flag's values
+const completionAnnotation = "cookie_completer"
+
+// completeCommand is the hidden command the completion scripts run, with the words of the
+// command line up to the cursor. It prints the candidates for the last word, one per line.
+const completeCommand = "__complete"
+
+// completers return the candidates for the values of the flags annotated with their name
+var completers = map[string]func() []string{
+	"browsers": func() []string { return supportedBrowsers },
+	"domains":  recentDomains,
+	"profiles": profileNames,
+	"formats": func() []string {
+		return []string{"json", "json-array", "jsonl", "netscape", "cookies.txt", "csv", "playwright", "har", "template", "header", "set-cookie", "gojar"}
+	},
+	"commands": func() []string { return requestCommands },
+	"dedupe":   func() []string { return []string{"latest-expiry", "longest-path", "per-store"} },
+	"matches":  func() []string { return []string{"contains", "exact", "suffix", "regex"} },
+	"groups":   func() []string { return []string{"store", "browser", "domain"} },
+	"keyrings": func() []string { return []string{"gnome", "kwallet", "basic", "none"} },
+}
+
+// completeWith attaches the completer to the flag, which has to be registered already
+func completeWith(fs *pflag.FlagSet, name string, completer string) {
+	fs.SetAnnotation(name, completionAnnotation, []string{completer})
+}
+
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+var completionScripts = map[string]string{
+	"bash": `_cookie() {
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(cookie __complete "${COMP_WORDS[@]:1:COMP_CWORD}")" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -o default -F _cookie cookie
+`,
+	"zsh": `#compdef cookie
+_cookie() {
+    local -a candidates
+    candidates=(${(f)"$(cookie __complete "${(@)words[2,CURRENT]}")"})
+    compadd -- $candidates
+}
+compdef _cookie cookie
+`,
+	"fish": `function __cookie_complete
+    set -l previous (commandline -opc)
+    set -l current (commandline -ct | string collect --allow-empty)
+    cookie __complete $previous[2..-1] $current
+end
+complete -c cookie -f -a '(__cookie_complete)'
+`,
+	"powershell": `Register-ArgumentCompleter -Native -CommandName cookie -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '' }
+    cookie __complete @words | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`,
+}
+
+// printCompletionScript prints the script loading the completion into shell
+func printCompletionScript(shell string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("%w: unsupported shell %q, use one of %s", errUsage, shell, strings.Join(completionShells, ", "))
+	}
+	return writeOutput(script)
+}
+
+// printCompletions prints the candidates for the last of words, the command line without the program name
+func printCompletions(w io.Writer, words []string) error {
+	current := ""
+	if len(words) > 0 {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	fs := pflag.NewFlagSet("complete", pflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	cmd := findCommand(words)
+	if cmd != nil {
+		cmd.flags(fs)
+	} else {
+		addFlatFlags(fs)
+	}
+	// the configured browser and the words typed so far decide which stores suggest domains
+	applyConfigDefaults(fs)
+	parsed := words
+	if cmd != nil {
+		parsed = words[1:]
+	}
+	fs.Parse(parsed)
+
+	var candidates []string
+	switch {
+	case len(words) > 0 && expectsValue(fs, words[len(words)-1]):
+		flag := lookupFlagWord(fs, words[len(words)-1])
+		if completer, ok := completers[firstAnnotation(flag)]; ok {
+			candidates = completer()
+		}
+
+	case strings.HasPrefix(current, "-"):
+		fs.VisitAll(func(flag *pflag.Flag) {
+			candidates = append(candidates, "--"+flag.Name)
+		})
+
+	case len(words) == 0:
+		for _, command := range commands {
+			candidates = append(candidates, command.name)
+			candidates = append(candidates, command.aliases...)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Fprintln(w, candidate)
+		}
+	}
+	return nil
+}
+
+// lookupFlagWord returns the flag of a word like --domain or -d, nil for anything else
+func lookupFlagWord(fs *pflag.FlagSet, word string) *pflag.Flag {
+	if strings.Contains(word, "=") {
+		return nil
+	}
+	if name, ok := strings.CutPrefix(word, "--"); ok {
+		return fs.Lookup(name)
+	}
+	if shorthand, ok := strings.CutPrefix(word, "-"); ok && len(shorthand) == 1 {
+		return fs.ShorthandLookup(shorthand)
+	}
+	return nil
+}
+
+// expectsValue reports whether word is a flag whose value is the next word
+func expectsValue(fs *pflag.FlagSet, word string) bool {
+	flag := lookupFlagWord(fs, word)
+	return flag != nil && flag.NoOptDefVal == ""
+}
+
+func firstAnnotation(flag *pflag.Flag) string {
+	if values := flag.Annotations[completionAnnotation]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// completionStoreTimeout bounds the read of a store while completing, a Tab shouldn't hang
+const completionStoreTimeout = 300 * time.Millisecond
+
+// recentDomains lists the cookie domains of the --browser stores, the most recently created first
+func recentDomains() []string {
+	timeout := completionStoreTimeout
+	if perStoreTimeout > 0 {
+		timeout = min(timeout, perStoreTimeout)
+	}
+	return cookielib.Domains(cookielib.Options{
+		Browser:         browser,
+		PerStoreTimeout: timeout,
+	})
+}
+
+// profileNames lists the profile names and directories of all discovered stores
+func profileNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, listing := range cookielib.ListStores() {
+		for _, name := range []string{listing.Profile, listing.Directory} {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// config holds the defaults of the configuration file, flags override them
+type config struct {
+	Browser string `yaml:"browser"`
+	Format  string `yaml:"format"`
+	Redact  *bool  `yaml:"redact"`
+	// Aliases are short names for --domain, e.g. work: sso.corp.example.com
+	Aliases map[string]string `yaml:"aliases"`
+}
+
+// configEnvironment maps the environment variables to the flags they set the default of,
+// they override the configuration file
+var configEnvironment = map[string]string{
+	"COOKIES_BROWSER": "browser",
+	"COOKIES_FORMAT":  "format",
+	"COOKIES_REDACT":  "redact",
+}
+
+var domainAliases map[string]string
+
+// configPath is $COOKIES_CONFIG or config.yaml in the cookies directory of the user configuration,
+// e.g. ~/.config/cookies/config.yaml on Linux
+func configPath() (string, error) {
+	if path := os.Getenv("COOKIES_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cookies", "config.yaml"), nil
+}
+
+// loadConfig reads the configuration file, a missing file is an empty configuration.
+// Only an explicitly given $COOKIES_CONFIG has to exist.
+func loadConfig() (config, error) {
+	var loaded config
+	path, err := configPath()
+	if err != nil {
+		return loaded, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && os.Getenv("COOKIES_CONFIG") == "" {
+		return loaded, nil
+	}
+	if err != nil {
+		return loaded, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		return loaded, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return loaded, nil
+}
+
+// applyConfigDefaults replaces the defaults of the registered flags by the values of the
+// configuration file and the environment. The flags don't count as changed, so they still
+// yield to explicit flags and are shown as the defaults in the usage.
+func applyConfigDefaults(fs *pflag.FlagSet) error {
+	loaded, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	domainAliases = loaded.Aliases
+
+	defaults := map[string]string{
+		"browser": loaded.Browser,
+		"format":  loaded.Format,
+	}
+	if loaded.Redact != nil {
+		defaults["redact"] = strconv.FormatBool(*loaded.Redact)
+	}
+	for variable, name := range configEnvironment {
+		if value, ok := os.LookupEnv(variable); ok {
+			defaults[name] = value
+		}
+	}
+
+	for name, value := range defaults {
+		flag := fs.Lookup(name)
+		if flag == nil || value == "" {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid default %q for flag '%s': %w", value, name, err)
+		}
+		flag.DefValue = value
+	}
+	return nil
+}
+
+// resolveDomainAlias returns the domain an alias of the configuration stands for
+func resolveDomainAlias(domain string) string {
+	if aliased, ok := domainAliases[domain]; ok {
+		return aliased
+	}
+	return domain
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+)
+
+// diagnostic is a single JSON line on stderr, so stdout only carries the cookie output
+type diagnostic struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+	// the store of a store error
+	Browser    string `json:"browser,omitempty"`
+	Profile    string `json:"profile,omitempty"`
+	Store      string `json:"store,omitempty"`
+	Decryption bool   `json:"decryption,omitempty"`
+}
+
+// logDiagnostic writes a diagnostic line with the given level (error, warning, info or debug),
+// errText is the error the message is about, if any
+func logDiagnostic(level string, message string, errText string) {
+	writeDiagnostic(diagnostic{Level: level, Message: message, Error: errText})
+}
+
+func writeDiagnostic(d diagnostic) {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	// messages quote templates and values, which are easier to read without \u003c escapes
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(d); err != nil {
+		// can't happen for strings, but a diagnostic must never get lost
+		fmt.Fprintf(os.Stderr, "%s: %s: %s\n", d.Level, d.Message, d.Error)
+		return
+	}
+	fmt.Fprint(os.Stderr, b.String())
+}
+
+func logWarning(format string, args ...interface{}) {
+	logDiagnostic("warning", fmt.Sprintf(format, args...), "")
+}
+
+// logStoreErrors reports the collected store errors
+func logStoreErrors() {
+	for _, err := range cookieStoreErrors {
+		reportStoreError(err)
+	}
+}
+
+// reportStoreError logs decryption failures and keyring password lookups of --keyring as warning,
+// as the whole store is missing from the output then. Other store errors are usually safe to
+// ignore and only logged with --log-debug.
+func reportStoreError(err error) {
+	switch {
+	case isDecryptionError(err), errors.Is(err, cookielib.ErrKeyring):
+		logStoreError("warning", err)
+	case debug:
+		logStoreError("debug", err)
+	}
+}
+
+// logStoreError writes the store of the error as separate fields
+func logStoreError(level string, err error) {
+	d := diagnostic{Level: level, Message: "cookie store error", Error: err.Error()}
+	var storeErr *cookielib.StoreError
+	if errors.As(err, &storeErr) {
+		d.Error = storeErr.Err.Error()
+		d.Browser, d.Profile, d.Store = storeErr.Browser, storeErr.Profile, storeErr.FilePath
+	}
+	if isDecryptionError(err) {
+		d.Message = "cookie values could not be decrypted, check access to the keyring or keychain or use --chromium-password or --keyring"
+		d.Decryption = true
+	}
+	writeDiagnostic(d)
+}
+
+func isDecryptionError(err error) bool {
+	var storeErr *cookielib.StoreError
+	return errors.As(err, &storeErr) && storeErr.Decryption()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+	"github.com/browserutils/kooky"
+)
+
+// cookieDiff is the output of the diff command
+type cookieDiff struct {
+	Left      string             `json:"left"`
+	Right     string             `json:"right"`
+	OnlyLeft  []servedCookie     `json:"onlyLeft"`
+	OnlyRight []servedCookie     `json:"onlyRight"`
+	Different []cookieDifference `json:"different"`
+}
+
+// cookieDifference is a cookie present on both sides, Fields lists the attributes that differ
+type cookieDifference struct {
+	Name   string       `json:"name"`
+	Domain string       `json:"domain"`
+	Path   string       `json:"path"`
+	Fields []string     `json:"fields"`
+	Left   servedCookie `json:"left"`
+	Right  servedCookie `json:"right"`
+}
+
+// diffCookies compares the cookies of --browser (or --store) with those of --against,
+// which is another browser or a file written by --full
+func diffCookies(browser string, domain string) error {
+	left, err := cookielib.Fetch(fetchOptions(browser, domain))
+	if err != nil && !errors.Is(err, errNoCookies) {
+		return fmt.Errorf("failed to obtain cookies: %w", err)
+	}
+
+	right, err := fetchDiffedCookies(domain)
+	if err != nil {
+		return err
+	}
+	logStoreErrors()
+
+	// the values are compared the way get and export would print them, e.g. with --trim or hashed
+	for _, side := range [][]cookielib.Cookie{left, right} {
+		values := make([]*kooky.Cookie, 0, len(side))
+		for _, cookie := range side {
+			values = append(values, cookie.Cookie)
+		}
+		transformCookieValues(values)
+	}
+
+	leftName := browser
+	if storeFile != "" {
+		leftName = storeFile
+	}
+	diff := compareCookies(indexDiffedCookies(left), indexDiffedCookies(right))
+	diff.Left, diff.Right = leftName, diffAgainst
+
+	diffJson, err := marshalJson(diff)
+	if err != nil {
+		return fmt.Errorf("failed to create JSON: %w", err)
+	}
+	return writeOutput(string(diffJson))
+}
+
+// fetchDiffedCookies reads the cookies of --against with the same filters as the other side
+func fetchDiffedCookies(domain string) ([]cookielib.Cookie, error) {
+	if !isSupportedBrowser(diffAgainst) {
+		if _, err := os.Stat(diffAgainst); err != nil {
+			return nil, fmt.Errorf("flag 'against' is neither a supported browser nor a readable file: %w", err)
+		}
+		read, err := readCookiesFromJsonFile(diffAgainst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cookies from %s: %w", diffAgainst, err)
+		}
+		filters, err := fetchOptions(browser, domain).Filters()
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain cookies: %w", err)
+		}
+
+		var cookies []cookielib.Cookie
+		for _, cookie := range kooky.FilterCookies(read, filters...) {
+			cookies = append(cookies, cookielib.Cookie{Cookie: cookie, FilePath: diffAgainst})
+		}
+		return cookies, nil
+	}
+
+	opts := fetchOptions(diffAgainst, domain)
+	opts.StoreFile = ""
+	opts.StoreType = ""
+	opts.Profiles = againstProfiles
+	cookies, err := cookielib.Fetch(opts)
+	if err != nil && !errors.Is(err, errNoCookies) {
+		return nil, fmt.Errorf("failed to obtain cookies of %s: %w", diffAgainst, err)
+	}
+	return cookies, nil
+}
+
+// indexDiffedCookies keys the cookies by name, domain and path. Of cookies that exist in
+// several profiles the one read last is kept, --profile and --against-profile pick one.
+func indexDiffedCookies(cookies []cookielib.Cookie) map[string]cookielib.Cookie {
+	indexed := make(map[string]cookielib.Cookie, len(cookies))
+	for _, cookie := range cookies {
+		indexed[normalizeName(cookie.Name)+"\x00"+cookie.Domain+"\x00"+cookie.Path] = cookie
+	}
+	return indexed
+}
+
+func compareCookies(left map[string]cookielib.Cookie, right map[string]cookielib.Cookie) cookieDiff {
+	diff := cookieDiff{
+		OnlyLeft:  []servedCookie{},
+		OnlyRight: []servedCookie{},
+		Different: []cookieDifference{},
+	}
+
+	var keys []string
+	for key := range left {
+		keys = append(keys, key)
+	}
+	for key := range right {
+		if _, ok := left[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		leftCookie, inLeft := left[key]
+		rightCookie, inRight := right[key]
+		switch {
+		case !inRight:
+			diff.OnlyLeft = append(diff.OnlyLeft, newDiffedCookie(leftCookie))
+		case !inLeft:
+			diff.OnlyRight = append(diff.OnlyRight, newDiffedCookie(rightCookie))
+		default:
+			fields := differingFields(leftCookie.Cookie, rightCookie.Cookie)
+			if len(fields) == 0 {
+				continue
+			}
+			diff.Different = append(diff.Different, cookieDifference{
+				Name:   leftCookie.Name,
+				Domain: leftCookie.Domain,
+				Path:   leftCookie.Path,
+				Fields: fields,
+				Left:   newDiffedCookie(leftCookie),
+				Right:  newDiffedCookie(rightCookie),
+			})
+		}
+	}
+
+	return diff
+}
+
+// differingFields compares the values before --redact, so masked values still show up as different
+func differingFields(left *kooky.Cookie, right *kooky.Cookie) []string {
+	var fields []string
+	if left.Value != right.Value {
+		fields = append(fields, "value")
+	}
+	// Firefox stores the expiry in seconds, Chromium in microseconds
+	if left.Expires.Unix() != right.Expires.Unix() {
+		fields = append(fields, "expires")
+	}
+	if left.Secure != right.Secure {
+		fields = append(fields, "secure")
+	}
+	if left.HttpOnly != right.HttpOnly {
+		fields = append(fields, "httpOnly")
+	}
+	return fields
+}
+
+func newDiffedCookie(cookie cookielib.Cookie) servedCookie {
+	diffed := newServedCookie(cookie)
+	if redact {
+		diffed.Value = redactValue(diffed.Value)
+	}
+	return diffed
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/browserutils/kooky"
+)
+
+// fullCookieRecord is the shape of a single cookie in the --full output
+type fullCookieRecord struct {
+	Cookie    *fullCookieAttributes
+	Creation  fullTime
+	Container string
+	// the store, missing in files of older versions
+	Browser string
+	Profile *string
+	Store   string
+}
+
+// fullCookieAttributes is the Cookie of a record, Expires shadows the one of http.Cookie
+type fullCookieAttributes struct {
+	http.Cookie
+	Expires fullTime
+}
+
+// fullTime is a time written with any --expiry-format: RFC 3339, epoch seconds, RFC 1123 or
+// the Go layout given with --expiry-format when reading. Session cookies have 0 or "".
+type fullTime time.Time
+
+func (t *fullTime) UnmarshalJSON(data []byte) error {
+	var seconds int64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		if seconds != 0 {
+			*t = fullTime(time.Unix(seconds, 0).UTC())
+		}
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("invalid time %s, expected a string or epoch seconds", data)
+	}
+	if text == "" {
+		return nil
+	}
+	layouts := []string{time.RFC3339Nano, time.RFC1123}
+	if _, ok := expiryFormatPresets[expiryFormat]; !ok && expiryFormat != "rfc3339" && expiryFormat != "unix" {
+		layouts = append(layouts, expiryFormat)
+	}
+	for _, layout := range layouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			*t = fullTime(parsed)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %q, expected RFC 3339, RFC 1123, epoch seconds or the layout of --expiry-format", text)
+}
+
+var errUnrecognizedJson = errors.New("unrecognized JSON, expected the output of --full (an array of cookies, or an object of cookies keyed by name as written by older versions)")
+
+// readCookiesFromJsonFile loads cookies that were previously dumped with --full or cookie snapshot
+func readCookiesFromJsonFile(filename string) ([]*kooky.Cookie, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	// a snapshot encrypted with a passphrase is read like the --full output it contains
+	data, err = openSnapshot(bytes.TrimSpace(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []fullCookieRecord
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, fmt.Errorf("%w: %s", errUnrecognizedJson, err)
+		}
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var recordsByName map[string]fullCookieRecord
+		if err := json.Unmarshal(trimmed, &recordsByName); err != nil {
+			return nil, fmt.Errorf("%w: %s", errUnrecognizedJson, err)
+		}
+		for _, record := range recordsByName {
+			records = append(records, record)
+		}
+	default:
+		return nil, errUnrecognizedJson
+	}
+
+	cookies := make([]*kooky.Cookie, 0, len(records))
+	for _, record := range records {
+		// a plain name/value map also decodes into records, but without any cookie details
+		if record.Cookie == nil || record.Cookie.Name == "" {
+			return nil, errUnrecognizedJson
+		}
+		cookie := &kooky.Cookie{
+			Cookie:    record.Cookie.Cookie,
+			Creation:  time.Time(record.Creation),
+			Container: record.Container,
+		}
+		cookie.Expires = time.Time(record.Cookie.Expires)
+		if record.Store != "" {
+			source := cookieSource{Browser: record.Browser, FilePath: record.Store}
+			if record.Profile != nil {
+				source.Profile = *record.Profile
+			}
+			cookieSources[cookie] = source
+		}
+		cookies = append(cookies, cookie)
+	}
+
+	return cookies, nil
+}
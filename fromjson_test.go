@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFullOutputRoundTrip(t *testing.T) {
+	for _, format := range []string{"rfc3339", "unix", "iso-local", "rfc1123", "2006-01-02 15:04:05"} {
+		t.Run(format, func(t *testing.T) {
+			resetFlags(t)
+			expiryFormat = format
+			cookies := goldenCookies()
+
+			output, err := serializeFullCookieInfoToJson(cookies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filename := filepath.Join(t.TempDir(), "full.json")
+			if err := os.WriteFile(filename, []byte(output), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			read, err := readCookiesFromJsonFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(read) != len(cookies) {
+				t.Fatalf("read %d cookies, want %d", len(read), len(cookies))
+			}
+			// --full sorts by name, domain and path, like the golden cookies are
+			for i, cookie := range read {
+				want := cookies[i]
+				if cookie.Name != want.Name || cookie.Domain != want.Domain {
+					t.Errorf("cookie %d: got %s on %s, want %s on %s", i, cookie.Name, cookie.Domain, want.Name, want.Domain)
+				}
+				if !cookie.Expires.Equal(want.Expires) || !cookie.Creation.Equal(want.Creation) {
+					t.Errorf("cookie %s: got expiry %v and creation %v, want %v and %v", cookie.Name, cookie.Expires, cookie.Creation, want.Expires, want.Creation)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/browserutils/kooky"
+	"github.com/spf13/pflag"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenCookies are the cookies of the golden tests. They are session cookies or expired ones,
+// so ExpiresIn doesn't depend on the time the tests run.
+func goldenCookies() []*kooky.Cookie {
+	created := time.Date(2019, 5, 4, 10, 0, 0, 0, time.UTC)
+	cookies := []*kooky.Cookie{
+		{
+			Cookie:   http.Cookie{Name: "sid", Value: "s3cr3t", Domain: ".example.com", Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode},
+			Creation: created,
+		},
+		{
+			Cookie:   http.Cookie{Name: "sid", Value: "old", Domain: "app.example.com", Path: "/app", Expires: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+			Creation: created,
+		},
+		{
+			Cookie:   http.Cookie{Name: "theme", Value: "", Domain: "example.com", Path: "/", Expires: time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)},
+			Creation: created,
+		},
+	}
+	cookieSources[cookies[0]] = cookieSource{Browser: "firefox", Profile: "default", FilePath: "/home/me/.mozilla/firefox/default/cookies.sqlite"}
+	cookieSources[cookies[1]] = cookieSource{Browser: "chrome", Profile: "Default", FilePath: "/home/me/.config/google-chrome/Default/Cookies"}
+	return cookies
+}
+
+// resetFlags sets all flags to their defaults
+func resetFlags(t *testing.T) {
+	t.Helper()
+	addFlatFlags(pflag.NewFlagSet("defaults", pflag.ContinueOnError))
+	cookieSources = make(map[*kooky.Cookie]cookieSource)
+}
+
+func checkGolden(t *testing.T, name string, output string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, []byte(output+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output+"\n" != string(want) {
+		t.Errorf("output differs from %s, rerun with -update if the change is intended\ngot:\n%s\nwant:\n%s", golden, output, want)
+	}
+}
+
+func TestGoldenOutput(t *testing.T) {
+	tests := []struct {
+		golden    string
+		setup     func()
+		serialize func([]*kooky.Cookie) (string, error)
+	}{
+		{"json.golden", func() {}, serializeCookiesToJson},
+		{"full.golden", func() { pretty = true }, serializeFullCookieInfoToJson},
+		{"full-unix.golden", func() { pretty = true; expiryFormat = "unix" }, serializeFullCookieInfoToJson},
+		{"human.golden", func() { domain = "example.com" }, func(cookies []*kooky.Cookie) (string, error) { return createHumanSummary(cookies), nil }},
+		{"full-group-by-store.golden", func() { pretty = true; groupBy = "store" }, serializeFullCookieInfoToJson},
+	}
+
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			resetFlags(t)
+			cookies := goldenCookies()
+			test.setup()
+
+			output, err := test.serialize(cookies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, test.golden, output)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+	"github.com/browserutils/kooky"
+)
+
+// the HAR 1.2 types, reduced to the fields the format requires and the cookies
+
+type harLog struct {
+	Log harContent `json:"log"`
+}
+
+type harContent struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            int         `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// harResponse is empty, as only the requests carry the cookies
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContentBody `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContentBody struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harTimings struct {
+	Send    int `json:"send"`
+	Wait    int `json:"wait"`
+	Receive int `json:"receive"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path"`
+	Domain   string `json:"domain"`
+	Expires  string `json:"expires,omitempty"`
+	HttpOnly bool   `json:"httpOnly"`
+	Secure   bool   `json:"secure"`
+}
+
+// serializeCookiesToHar writes one GET request per cookie domain, carrying the cookies of that domain
+func serializeCookiesToHar(cookies []*kooky.Cookie) (string, error) {
+	byDomain := make(map[string][]*kooky.Cookie)
+	for _, cookie := range sortedCookies(cookies) {
+		host := strings.TrimPrefix(cookie.Domain, ".")
+		byDomain[host] = append(byDomain[host], cookie)
+	}
+
+	hosts := make([]string, 0, len(byDomain))
+	for host := range byDomain {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	started := time.Now().UTC().Format(time.RFC3339)
+	entries := make([]harEntry, 0, len(hosts))
+	for _, host := range hosts {
+		hostCookies := byDomain[host]
+		request := harRequest{
+			Method:      "GET",
+			URL:         requestScheme(hostCookies) + "://" + host + "/",
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     make([]harCookie, 0, len(hostCookies)),
+			Headers:     []harNameValue{{Name: "Cookie", Value: cookielib.CookieHeader(sourcedCookies(hostCookies))}},
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		for _, cookie := range hostCookies {
+			entry := harCookie{
+				Name:     cookie.Name,
+				Value:    cookie.Value,
+				Path:     cookie.Path,
+				Domain:   cookie.Domain,
+				HttpOnly: cookie.HttpOnly,
+				Secure:   cookie.Secure,
+			}
+			if !cookielib.IsSession(cookie) {
+				entry.Expires = cookie.Expires.UTC().Format(time.RFC3339)
+			}
+			request.Cookies = append(request.Cookies, entry)
+		}
+
+		entries = append(entries, harEntry{
+			StartedDateTime: started,
+			Request:         request,
+			Response: harResponse{
+				Cookies: []harCookie{},
+				Headers: []harNameValue{},
+				Content: harContentBody{MimeType: "x-unknown"},
+				// -1 is the HAR value for unknown sizes
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+		})
+	}
+
+	harJsonBytes, err := marshalJson(harLog{Log: harContent{
+		Version: "1.2",
+		Creator: harCreator{Name: "cookies", Version: "1"},
+		Entries: entries,
+	}})
+	if err != nil {
+		return "", err
+	}
+
+	return string(harJsonBytes), nil
+}
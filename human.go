@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+	"github.com/browserutils/kooky"
+)
+
+const humanValueLength = 40
+
+// createHumanSummary renders the cookies as a short readable list for interactive use
+func createHumanSummary(cookies []*kooky.Cookie) string {
+	var b strings.Builder
+
+	target := domain
+	if target == "" {
+		target = domainGlob
+	}
+	fmt.Fprintf(&b, "Found %d cookies", len(cookies))
+	if target != "" {
+		fmt.Fprintf(&b, " for %s", target)
+	}
+	if browsers := summaryBrowsers(cookies); len(browsers) > 0 {
+		fmt.Fprintf(&b, " across %s", strings.Join(browsers, ", "))
+	}
+	b.WriteString(":\n")
+
+	for _, cookie := range cookies {
+		var attributes []string
+		if cookie.Secure {
+			attributes = append(attributes, "secure")
+		}
+		if cookie.HttpOnly {
+			attributes = append(attributes, "httponly")
+		}
+		attributes = append(attributes, describeExpiry(cookie))
+
+		fmt.Fprintf(&b, "  %s: %s [%s]\n", cookie.Name, truncateValue(cookie.Value), strings.Join(attributes, ", "))
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// summaryBrowsers are the distinct browsers the cookies come from, sorted
+func summaryBrowsers(cookies []*kooky.Cookie) []string {
+	seen := make(map[string]bool)
+	var browsers []string
+	for _, cookie := range cookies {
+		name := cookieBrowser(cookie)
+		if !seen[name] {
+			seen[name] = true
+			browsers = append(browsers, name)
+		}
+	}
+	sort.Strings(browsers)
+	return browsers
+}
+
+func truncateValue(value string) string {
+	runes := []rune(value)
+	if len(runes) <= humanValueLength {
+		return value
+	}
+	return string(runes[:humanValueLength]) + "…"
+}
+
+func describeExpiry(cookie *kooky.Cookie) string {
+	if cookielib.IsSession(cookie) {
+		return "session"
+	}
+
+	remaining := time.Until(cookie.Expires)
+	if remaining <= 0 {
+		return "expired"
+	}
+
+	return "expires in " + humanDuration(remaining)
+}
+
+// humanDuration formats durations with the two largest units, e.g. 3d4h or 5m
+func humanDuration(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return "less than a minute"
+	}
+}
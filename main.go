@@ -1,234 +1,1962 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"reflect"
-	"strconv"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"text/template"
+	"time"
 
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+	"github.com/What-is-water93/cookies/pkg/cookiesjar"
 	"github.com/browserutils/kooky"
-	_ "github.com/browserutils/kooky/browser/chrome"
-	_ "github.com/browserutils/kooky/browser/firefox"
 	"github.com/spf13/pflag"
 )
 
 var (
-	browser           string
-	curl              bool
-	domain            string
-	name              string
-	fullCookieInfo    bool
-	showExpired       bool
-	help              bool
-	cookieStoreErrors []string
-	debug             bool
+	browser            string
+	curl               bool
+	wget               bool
+	requestCommand     string
+	targetURL          string
+	domain             string
+	names              []string
+	fullCookieInfo     bool
+	showExpired        bool
+	help               bool
+	cookieStoreErrors  []error
+	debug              bool
+	registrableDomain  bool
+	exactDomain        bool
+	domainMatch        string
+	pathPrefix         string
+	container          string
+	secureOnly         bool
+	httpOnly           bool
+	dumpRaw            bool
+	showValues         bool
+	thisSession        bool
+	rootKey            string
+	groupBy            string
+	hashValues         bool
+	hashLength         int
+	redact             bool
+	limit              int
+	appendOutput       bool
+	printingCompletion bool
+	completionShell    string
+	diffing            bool
+	diffAgainst        string
+	againstProfiles    []string
+	profiles           []string
+	excludeProfiles    []string
+	excludeGlobs       []string
+	cookiejarGo        bool
+	setCookie          bool
+	scheme             string
+	maxAge             bool
+	canonical          bool
+	pretty             bool
+	excludeSession     bool
+	sessionOnly        bool
+	expiresWithin      time.Duration
+	expiresAfterText   string
+	expiresAfter       time.Time
+	nonEmpty           bool
+	retryOnEmpty       int
+	retryDelay         time.Duration
+	preferNewestStore  bool
+	valueLengths       bool
+	trimValues         bool
+	domainGlob         string
+	preferHttpOnly     bool
+	dedupePolicy       string
+	diagnosePerms      bool
+	fromJson           string
+	snapshotting       bool
+	restoring          bool
+	encryptSnapshot    bool
+	passphraseFile     string
+	summary            bool
+	failFast           bool
+	stripPrefix        string
+	storeFile          string
+	storeType          string
+	chromiumPassword   string
+	keyring            string
+	maxCookies         int
+	secretsDir         string
+	nullIfMissing      bool
+	localTime          bool
+	perStoreTimeout    time.Duration
+	normalizeNames     bool
+	lowercaseNames     bool
+	inventory          bool
+	count              bool
+	nameDomains        []string
+	nameDomainPairs    []cookielib.NameDomain
+	nameGlobs          []string
+	nameRegexExpr      string
+	nameRegex          *regexp.Regexp
+	valueEncoding      string
+	listStoresJson     bool
+	storesTable        bool
+	valuePrefix        string
+	human              bool
+	browse             bool
+	watch              bool
+	copyValue          bool
+	alsoPrint          bool
+	watchInterval      time.Duration
+	expectCount        int
+	expectMinCount     int
+	printSchema        bool
+	mergeStrategy      string
+	excludeValueExpr   string
+	excludeValueRegex  *regexp.Regexp
+	pickDomains        bool
+	expiryFormat       string
+	outputFormat       string
+	templateText       string
+	templateAll        bool
+	outputTemplate     *template.Template
+	outputFile         string
+	serveAddress       string
+	cookieSources      = make(map[*kooky.Cookie]cookieSource)
+	flagSet            = pflag.CommandLine
 )
 
-func printUsage() {
-	fmt.Println("Obtain cookies from your browser stores")
-	fmt.Println("\nUse with the following flags:")
-	pflag.CommandLine.SortFlags = false
-	pflag.PrintDefaults()
+// cookieSource records the store a cookie was read from
+type cookieSource struct {
+	Browser  string
+	Profile  string
+	FilePath string
+}
+
+// supportedBrowsers are the valid values of --browser, 'all' reads the stores of every browser
+// and 'recent' those of the browser whose store was modified last
+var supportedBrowsers = []string{"chrome", "chromium", "edge", "brave", "vivaldi", "opera", "firefox", "safari", "electron", "all", "recent"}
+
+var (
+	errUsage           = errors.New("incorrect flag usage")
+	errNoCookies       = cookielib.ErrNoCookies
+	errCookieNotExists = errors.New("cookie does not exist")
+	errCookieAmbiguous = errors.New("cookie name is ambiguous")
+)
+
+func printUsage(fs *pflag.FlagSet, description string) {
+	fmt.Println(description)
+	if fs == pflag.CommandLine {
+		fmt.Println("\nCommands:")
+		for _, cmd := range commands {
+			fmt.Printf("  %-10s %s\n", cmd.names(), cmd.description)
+		}
+		fmt.Println("\nRun 'cookie <command> -h' for the flags of a command, or use the flags below without a command:")
+	} else {
+		fmt.Println("\nUse with the following flags:")
+	}
+	fs.SortFlags = false
+	fs.PrintDefaults()
 
 	os.Exit(0)
 }
 
+// addStoreFlags adds the flags selecting and reading the cookie stores
+func addStoreFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&browser, "browser", "b", "chrome", "The browser you want to obtain cookies from: "+strings.Join(supportedBrowsers, ", ")+" or a comma separated list like 'chrome,firefox'. 'electron' reads the stores of Slack, VS Code and Discord, 'all' the stores of every other browser, 'recent' picks the most recently used browser")
+	completeWith(fs, "browser", "browsers")
+	fs.StringVar(&storeFile, "store", "", "reads the given cookie store file instead of discovering the browser's stores")
+	fs.StringVar(&storeFile, "store-file", "", "same as --store")
+	fs.StringVar(&storeFile, "file", "", "same as --store")
+	fs.StringVar(&storeType, "store-type", "", "forces the reader for --store: "+cookielib.StoreTypes+". Taken from an explicit --browser or detected from the file name if empty")
+	fs.BoolVar(&preferNewestStore, "prefer-newest-store", false, "only read the most recently modified cookie store of the browser")
+	fs.StringArrayVar(&profiles, "profile", nil, "only read the cookie stores of the given profile, by profile name or directory like 'Profile 1' (repeatable). See --list-stores for the profiles")
+	completeWith(fs, "profile", "profiles")
+	fs.StringArrayVar(&excludeProfiles, "exclude-profile", nil, "skip cookie stores of the given profile name (repeatable)")
+	completeWith(fs, "exclude-profile", "profiles")
+	fs.StringArrayVar(&excludeGlobs, "exclude-profile-glob", nil, "skip cookie stores whose profile name matches the glob pattern (repeatable)")
+	fs.StringVar(&chromiumPassword, "chromium-password", "", "decrypts the values of Chromium based browsers with the given keyring password ('Chrome Safe Storage') instead of asking the keyring, e.g. in containers without keyring. Also read from $COOKIES_CHROMIUM_PASSWORD, which doesn't show up in the process list")
+	fs.StringVar(&keyring, "keyring", "", "where the Chromium password comes from on Linux: "+cookielib.Keyrings+" (Chromium's fixed key without keyring). Empty asks the Secret Service")
+	completeWith(fs, "keyring", "keyrings")
+	fs.DurationVar(&perStoreTimeout, "per-store-timeout", 0, "gives up on a single cookie store after the duration and continues with the next one, e.g. 2s")
+	fs.DurationVar(&perStoreTimeout, "timeout", 0, "same as --per-store-timeout")
+	fs.BoolVar(&failFast, "fail-fast", false, "abort on the first cookie store error instead of ignoring it")
+	fs.BoolVarP(&debug, "log-debug", "l", false, "logs cookie store errors, which are usually safe to ignore")
+	fs.BoolVar(&dumpRaw, "dump-raw", false, "prints the unfiltered cookie names and domains of every store to stderr")
+	fs.BoolVar(&showValues, "show-values", false, "include cookie values in the --dump-raw output")
+	fs.IntVar(&retryOnEmpty, "retry-on-empty", 0, "re-read the stores up to N times if no cookies match, e.g. while the browser still flushes them to disk")
+	fs.DurationVar(&retryDelay, "retry-delay", time.Second, "delay between the retries of --retry-on-empty")
+	fs.BoolVar(&summary, "summary", false, "prints a single status line with cookie and store error counts to stderr")
+}
+
+// addFilterFlags adds the flags deciding which of the cookies are returned
+func addFilterFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&domain, "domain", "d", "", "cookie domain filter, partial (contains) match unless --exact-domain is given. Required")
+	completeWith(fs, "domain", "domains")
+	fs.StringVar(&fromJson, "from-json", "", "reads cookies from a file produced by --full instead of the browser stores")
+	fs.StringVar(&domainGlob, "domain-glob", "", "cookie domain filter using a glob pattern, e.g. '*.example.*'. Makes --domain optional")
+	fs.StringVar(&domainMatch, "match", "contains", "how --domain is matched: contains, exact (same as --exact-domain), suffix (the domain and its subdomains, not lookalikes like badexample.com) or regex (--domain is a regular expression for the domain without leading dot)")
+	completeWith(fs, "match", "matches")
+	fs.BoolVar(&exactDomain, "exact-domain", false, "match --domain exactly instead of partially, a leading dot of domain cookies is ignored")
+	fs.BoolVar(&registrableDomain, "registrable-domain", false, "match cookies on the registrable domain (eTLD+1) of --domain and its subdomains")
+	fs.StringSliceVar(&nameDomains, "name-domain", nil, "only returns the given exact name@domain pairs, e.g. 'sessionid@example.com,csrf@api.example.com'. Makes --domain optional")
+	fs.StringArrayVar(&nameGlobs, "name-glob", nil, "only returns cookies whose name matches the glob pattern, e.g. 'session*' (repeatable)")
+	fs.StringVar(&nameRegexExpr, "name-regex", "", "only returns cookies whose name matches the regular expression, e.g. '^csrf'")
+	fs.BoolVar(&pickDomains, "pick-domain", false, "asks which domain to use if --domain matches several (only on a terminal)")
+	fs.StringVar(&pathPrefix, "path", "", "only returns cookies whose path starts with the given prefix, e.g. '/api'")
+	fs.BoolVar(&secureOnly, "secure-only", false, "only returns cookies with the Secure flag")
+	fs.BoolVar(&httpOnly, "http-only", false, "only returns cookies with the HttpOnly flag")
+	fs.StringVar(&container, "container", "", "only returns Firefox cookies of the given container, by name like 'Work' or id")
+	fs.StringVar(&valuePrefix, "value-prefix", "", "only returns cookies whose value starts with the given string, e.g. 'eyJ' for JWTs")
+	fs.StringVar(&excludeValueExpr, "exclude-value-regex", "", "drops cookies whose value matches the regular expression, applied after all other filters")
+	fs.BoolVarP(&showExpired, "expired", "e", false, "show expired cookies")
+	fs.BoolVar(&excludeSession, "exclude-session", false, "drop session cookies (cookies without an expiry)")
+	fs.DurationVar(&expiresWithin, "expires-within", 0, "only return cookies that expire within the duration, e.g. 24h. Session cookies never match")
+	fs.StringVar(&expiresAfterText, "expires-after", "", "only return cookies that expire after the RFC 3339 time, e.g. 2024-06-01T00:00:00Z. Session cookies never match")
+	fs.BoolVar(&sessionOnly, "session-only", false, "only return session cookies (cookies without an expiry)")
+	fs.BoolVar(&nonEmpty, "non-empty", false, "drop cookies with an empty value")
+	fs.BoolVar(&thisSession, "this-session", false, "only show cookies created since the running browser was launched")
+	fs.BoolVar(&normalizeNames, "normalize-names", false, "trims whitespace from cookie names used as JSON keys and for matching")
+	fs.BoolVar(&lowercaseNames, "lowercase-names", false, "also lowercases the names with --normalize-names")
+	fs.StringVar(&mergeStrategy, "merge-strategy", "", "how cookies of several stores are combined: empty merges all of them, 'profile-unit' takes all cookies from the single best profile")
+	fs.BoolVar(&preferHttpOnly, "prefer-httponly", false, "if several cookies share a name, keep the HttpOnly one")
+	fs.StringVar(&dedupePolicy, "dedupe", "", "if several cookies share a name, keep only one: latest-expiry, longest-path or per-store (the cookies of a single store)")
+	completeWith(fs, "dedupe", "dedupe")
+	fs.IntVar(&expectCount, "expect-count", -1, "fail unless exactly N cookies match")
+	fs.IntVar(&expectMinCount, "expect-min-count", -1, "fail unless at least N cookies match")
+	fs.IntVar(&limit, "limit", 0, "stops reading the stores once N cookies matched and only returns those, e.g. --limit 1 with --name for the fastest lookup. 0 means unlimited")
+	fs.IntVar(&maxCookies, "max-cookies", 0, "fail if more than N cookies match, as a guard against too broad filters. 0 means unlimited")
+}
+
+// addValueFlags adds the flags transforming the cookie values before any output
+func addValueFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&trimValues, "trim", false, "removes leading and trailing whitespace from cookie values")
+	fs.StringVar(&stripPrefix, "strip-prefix", "", "removes the given prefix from cookie values that start with it, e.g. 's%3A' or 'Bearer '")
+	fs.StringVar(&valueEncoding, "encoding", "utf8", "how cookie values are represented: utf8 (as is), latin1 (decoded from ISO-8859-1) or raw-base64")
+	fs.BoolVar(&hashValues, "hash-values", false, "replaces each cookie value with its hex encoded SHA-256 hash")
+	fs.IntVar(&hashLength, "hash-length", sha256.Size*2, "number of hex characters of the hash to keep with --hash-values")
+	fs.BoolVar(&redact, "redact", false, "masks each cookie value except its first and last 4 characters and length, the commands of --curl get a placeholder")
+}
+
+// addGetFlags adds the JSON and other printing output modes
+func addGetFlags(fs *pflag.FlagSet) {
+	fs.StringArrayVarP(&names, "name", "n", nil, "prints only the value of the given cookie (exact name match). Repeatable, several names print a JSON map with null for missing cookies")
+	fs.BoolVar(&nullIfMissing, "null-if-missing", false, "prints null and exits successfully if the cookie given by --name doesn't exist")
+	fs.BoolVar(&copyValue, "copy", false, "copies the value of the cookie given by --name to the clipboard instead of printing it")
+	fs.BoolVar(&alsoPrint, "also-print", false, "prints the value copied by --copy as well")
+	fs.BoolVarP(&fullCookieInfo, "full", "f", false, "outputs full information about each cookie")
+	fs.BoolVar(&count, "count", false, "outputs the number of cookies in total, per browser and per domain without any values")
+	fs.BoolVar(&inventory, "inventory", false, "outputs per domain counts of all, secure, httponly and session cookies without any values")
+	fs.BoolVar(&valueLengths, "lengths", false, "outputs a JSON map of cookie names to the length of their values instead of the values")
+	fs.BoolVar(&human, "human", false, "prints a readable summary of the cookies instead of JSON")
+	fs.StringVar(&secretsDir, "secrets-dir", "", "writes each cookie value to its own file <dir>/<name> with 0600 permissions")
+	fs.BoolVar(&setCookie, "set-cookie", false, "outputs a Set-Cookie response header line per cookie")
+	fs.BoolVar(&cookiejarGo, "cookiejar-go", false, "outputs Go source with []*http.Cookie literals for populating an http.CookieJar")
+	fs.BoolVarP(&pretty, "pretty", "p", false, "indents the JSON output with two spaces")
+	fs.BoolVar(&canonical, "canonical", false, "outputs canonical JSON (RFC 8785), e.g. for hashing or signing")
+	fs.StringVar(&rootKey, "root-key", "", "wraps the JSON cookie map under the given key")
+	fs.StringVar(&groupBy, "group-by", "", "nests the JSON output and --full under the store file, browser or domain of the cookies: store, browser or domain")
+	completeWith(fs, "group-by", "groups")
+	fs.StringVar(&expiryFormat, "expiry-format", "rfc3339", "format of the times in the full output: rfc3339, unix, iso-local, rfc1123 or a Go time layout like '2006-01-02 15:04'")
+	fs.BoolVar(&localTime, "local-time", false, "shows times in the full output in the local timezone instead of UTC")
+	fs.BoolVar(&maxAge, "max-age", false, "adds a MaxAge field (seconds until expiry) to the full output and a Max-Age attribute to --set-cookie")
+	fs.BoolVar(&watch, "watch", false, "keeps reading the stores and prints a JSON line for every matching cookie that is added, changed or removed")
+	fs.DurationVar(&watchInterval, "watch-interval", 2*time.Second, "delay between the reads of --watch")
+}
+
+func addSchemeFlag(fs *pflag.FlagSet) {
+	fs.StringVar(&requestCommand, "command", "", "outputs a command requesting the URL with the cookies: "+strings.Join(requestCommands, ", "))
+	completeWith(fs, "command", "commands")
+	fs.StringVar(&targetURL, "url", "", "URL of the generated command instead of the --domain, e.g. 'https://api.example.com/me'")
+	fs.StringVar(&scheme, "scheme", "", "URL scheme for the curl and wget command (http or https), inferred from the cookies' Secure flags if empty")
+}
+
+func addFormatFlag(fs *pflag.FlagSet, defaultFormat string) {
+	fs.StringVar(&outputFormat, "format", defaultFormat, "output format of the cookies: json, jsonl (a JSON object per line and cookie), netscape or cookies.txt (a cookies.txt file for curl -b, wget --load-cookies or yt-dlp --cookies), csv, playwright (a storageState for Playwright and Puppeteer) har (an HTTP Archive with a request per domain), template (see --template), json-array (same as --full), header (the value of a Cookie header), set-cookie (same as --set-cookie) or gojar (a file for cookiesjar.Load)")
+	completeWith(fs, "format", "formats")
+	fs.StringVar(&templateText, "template", "", "Go text/template for --format template, executed per cookie, e.g. '{{.Name}}={{.Value}}'")
+	fs.BoolVar(&templateAll, "template-all", false, "executes --template once with the list of all cookies instead of once per cookie")
+}
+
+func addOutputFlag(fs *pflag.FlagSet) {
+	fs.StringVarP(&outputFile, "output", "o", "", "writes the output to the given file (created with 0600 permissions) instead of stdout")
+	fs.StringVar(&outputFile, "out", "", "same as --output")
+	fs.BoolVar(&appendOutput, "append", false, "appends to the --output file instead of replacing it, for --format netscape, --format jsonl and --watch")
+}
+
+// addFlatFlags adds every flag for the invocation without a command
+func addFlatFlags(fs *pflag.FlagSet) {
+	addStoreFlags(fs)
+	addFilterFlags(fs)
+	fs.BoolVarP(&curl, "curl", "c", false, "outputs a curl command using all valid existing cookies for domain")
+	fs.BoolVar(&wget, "wget", false, "outputs a wget command using all valid existing cookies for domain")
+	addSchemeFlag(fs)
+	addOutputFlag(fs)
+	addFormatFlag(fs, "json")
+	addGetFlags(fs)
+	addValueFlags(fs)
+	fs.BoolVar(&listStoresJson, "list-stores-json", false, "prints all discovered cookie stores as JSON and exits. Doesn't need --domain")
+	fs.BoolVar(&listStoresJson, "list-stores", false, "same as --list-stores-json")
+	fs.BoolVar(&printSchema, "schema", false, "prints the available cookie field names and types and exits. Doesn't need --domain")
+	fs.BoolVar(&diagnosePerms, "diagnose-permissions", false, "checks whether every discovered cookie store file can be read and exits. Doesn't need --domain")
+}
+
 func parseFlags() error {
-	pflag.StringVarP(&domain, "domain", "d", "", "cookie domain filter (partial). Required")
-	pflag.StringVarP(&browser, "browser", "b", "chrome", "The browser you want to obtain cookies from")
-	pflag.BoolVarP(&curl, "curl", "c", false, "outputs a curl command using all valid existing cookies for domain")
-	pflag.BoolVarP(&showExpired, "expired", "e", false, "show expired cookies")
-	pflag.BoolVarP(&fullCookieInfo, "full", "f", false, "outputs full information about each cookie")
-	pflag.StringVarP(&name, "name", "n", "", "prints only the value of the given cookie (exact name match)")
-	pflag.BoolVarP(&debug, "log-debug", "l", false, "logs cookie store errors, which are usually safe to ignore")
-	pflag.BoolVarP(&help, "help", "h", false, "display usage information")
-	pflag.Parse()
+	description := "Obtain cookies from your browser stores"
+	args := os.Args[1:]
+	if cmd := findCommand(args); cmd != nil {
+		// registering every flag once sets the defaults of those the command doesn't have
+		addFlatFlags(pflag.NewFlagSet("defaults", pflag.ContinueOnError))
+		flagSet = pflag.NewFlagSet("cookie "+cmd.name, pflag.ExitOnError)
+		flagSet.SortFlags = false
+		cmd.flags(flagSet)
+		description = cmd.description
+		args = args[1:]
+	} else {
+		addFlatFlags(flagSet)
+	}
+	flagSet.BoolVarP(&help, "help", "h", false, "display usage information")
+	if err := applyConfigDefaults(flagSet); err != nil {
+		return err
+	}
+	flagSet.Parse(args)
+	domain = resolveDomainAlias(domain)
+
+	// a command like stores works without any flag
+	if help || (flagSet == pflag.CommandLine && flagSet.NFlag() == 0) {
+		printUsage(flagSet, description)
+	}
+
+	if cmd := findCommand(os.Args[1:]); cmd != nil && cmd.apply != nil {
+		cmd.apply()
+	}
+
+	// diagnostics don't read any cookies, so no filter is needed
+	if diagnosePerms || listStoresJson || printSchema || printingCompletion {
+		return nil
+	}
+
+	listedBrowsers := strings.Split(browser, ",")
+	for _, listed := range listedBrowsers {
+		if !isSupportedBrowser(listed) {
+			return fmt.Errorf("unsupported browser: %s", listed)
+		}
+		if len(listedBrowsers) > 1 && (listed == "all" || listed == "recent" || listed == "electron") {
+			return fmt.Errorf("browser %s can't be combined with other browsers", listed)
+		}
+	}
+
+	for _, pair := range nameDomains {
+		parsed, err := parseNameDomainPair(pair)
+		if err != nil {
+			return err
+		}
+		nameDomainPairs = append(nameDomainPairs, parsed)
+	}
+
+	switch domainMatch {
+	case "contains", "exact", "suffix", "regex":
+	default:
+		return errors.New("flag 'match' must be one of contains, exact, suffix or regex")
+	}
+
+	if exactDomain {
+		if domainMatch != "contains" && domainMatch != "exact" {
+			return errors.New("flag 'exact-domain' and flag 'match' are mutually exclusive")
+		}
+		domainMatch = "exact"
+	}
+	exactDomain = domainMatch == "exact"
+
+	if domainMatch != "contains" && registrableDomain {
+		return errors.New("flag 'match' and flag 'registrable-domain' are mutually exclusive")
+	}
+
+	if domainMatch == "regex" {
+		if _, err := regexp.Compile(domain); err != nil {
+			return fmt.Errorf("invalid regular expression for flag 'domain' with --match regex: %w", err)
+		}
+	}
+
+	// the domain of the served cookies is a request parameter
+	// snapshots and restores take all cookies without --domain
+	if serveAddress == "" && domain == "" && ((domainGlob == "" && len(nameDomainPairs) == 0 && !snapshotting && !restoring) || domainMatch != "contains") {
+		return errors.New("flag domain is required, use either -d $DOMAIN or --domain $DOMAIN")
+	}
+
+	if domainGlob != "" {
+		if _, err := path.Match(domainGlob, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q for flag 'domain-glob': %w", domainGlob, err)
+		}
+	}
+
+	if curl && wget {
+		return errors.New("flag 'curl' and flag 'wget' are mutually exclusive")
+	}
+
+	if requestCommand != "" && (curl || wget) {
+		return errors.New("flag 'command' can't be combined with flag 'curl' or flag 'wget'")
+	}
+
+	// --curl and --wget are shorthands of --command
+	if curl {
+		requestCommand = "curl"
+	} else if wget {
+		requestCommand = "wget"
+	}
+
+	if requestCommand != "" && !isRequestCommand(requestCommand) {
+		return fmt.Errorf("flag 'command' must be one of %s", strings.Join(requestCommands, ", "))
+	}
+
+	if targetURL != "" {
+		if requestCommand == "" {
+			return errors.New("flag 'url' needs flag 'command', flag 'curl' or flag 'wget'")
+		}
+		if scheme != "" {
+			return errors.New("flag 'url' and flag 'scheme' are mutually exclusive")
+		}
+		if parsed, err := url.Parse(targetURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("flag 'url' must be an absolute http or https URL, got %q", targetURL)
+		}
+	}
+
+	if domain == "" && ((requestCommand != "" && targetURL == "") || registrableDomain) {
+		return errors.New("flag 'command' without flag 'url', flag 'curl', flag 'wget' and flag 'registrable-domain' need flag domain")
+	}
+
+	if exactDomain && registrableDomain {
+		return errors.New("flag 'exact-domain' and flag 'registrable-domain' are mutually exclusive")
+	}
+
+	if domainMatch == "regex" && requestCommand != "" && targetURL == "" {
+		return errors.New("flag 'command' (or 'curl', 'wget') with --match regex needs flag 'url', the domain isn't a host name")
+	}
+
+	if requestCommand != "" && len(names) > 0 {
+		return errors.New("flag 'command' (or 'curl', 'wget') and flag 'name' are mutually exclusive")
+	}
+
+	if scheme != "" && scheme != "http" && scheme != "https" {
+		return errors.New("flag 'scheme' must be either http or https")
+	}
+
+	if lowercaseNames && !normalizeNames {
+		return errors.New("flag 'lowercase-names' needs flag 'normalize-names'")
+	}
+
+	if nullIfMissing && len(names) == 0 {
+		return errors.New("flag 'null-if-missing' needs flag 'name'")
+	}
+
+	if storeType != "" && storeFile == "" {
+		return errors.New("flag 'store-type' needs flag 'store'")
+	}
+
+	// cookies.txt is what most downloaders call the Netscape format
+	if outputFormat == "cookies.txt" {
+		outputFormat = "netscape"
+	}
+
+	// the full output is the JSON that keeps every cookie
+	if outputFormat == "json-array" {
+		outputFormat = "json"
+		fullCookieInfo = true
+	}
+
+	if outputFormat == "set-cookie" {
+		outputFormat = "json"
+		setCookie = true
+	}
+
+	switch outputFormat {
+	case "json", "jsonl", "netscape", "csv", "playwright", "har", "template", "header", "gojar":
+	default:
+		return errors.New("flag 'format' must be one of json, json-array, jsonl, netscape, csv, playwright, har, template, header, set-cookie or gojar")
+	}
+
+	if (outputFormat == "template") != (templateText != "") {
+		return errors.New("flag 'format' template and flag 'template' need each other")
+	}
+
+	if templateAll && templateText == "" {
+		return errors.New("flag 'template-all' needs flag 'template'")
+	}
+
+	if templateText != "" {
+		parsed, err := parseOutputTemplate(templateText)
+		if err != nil {
+			return fmt.Errorf("invalid template for flag 'template': %w", err)
+		}
+		outputTemplate = parsed
+	}
+
+	// a configured format is only a default, an explicit output mode wins over it
+	if !flagSet.Changed("format") && otherOutputMode() {
+		outputFormat = "json"
+	}
+
+	if outputFormat != "json" && otherOutputMode() {
+		return fmt.Errorf("flag 'format' %s can't be combined with another output mode", outputFormat)
+	}
+
+	if pretty && canonical {
+		return errors.New("flag 'pretty' and flag 'canonical' are mutually exclusive")
+	}
+
+	if expiresWithin < 0 {
+		return errors.New("flag 'expires-within' can't be negative")
+	}
+
+	if expiresWithin > 0 && showExpired {
+		return errors.New("flag 'expires-within' and flag 'expired' are mutually exclusive")
+	}
+
+	if expiresAfterText != "" {
+		parsed, err := time.Parse(time.RFC3339, expiresAfterText)
+		if err != nil {
+			return fmt.Errorf("flag 'expires-after' must be an RFC 3339 time like 2024-06-01T00:00:00Z: %w", err)
+		}
+		expiresAfter = parsed
+	}
+
+	if sessionOnly && excludeSession {
+		return errors.New("flag 'session-only' and flag 'exclude-session' are mutually exclusive")
+	}
+
+	if setCookie && (requestCommand != "" || len(names) > 0 || cookiejarGo) {
+		return errors.New("flag 'set-cookie' can't be combined with flag 'command' (or 'curl', 'wget'), flag 'name' or flag 'cookiejar-go'")
+	}
+
+	if copyValue && len(names) != 1 {
+		return errors.New("flag 'copy' needs exactly one flag 'name'")
+	}
+
+	if !flagSet.Changed("redact") && (hashValues || copyValue) {
+		redact = false
+	}
+
+	if redact && (hashValues || copyValue) {
+		return errors.New("flag 'redact' can't be combined with flag 'hash-values' or flag 'copy'")
+	}
+
+	if restoring && fromJson == "" {
+		return errors.New("the snapshot to restore is missing, e.g. cookie restore snapshot.json.enc --out cookies.txt")
+	}
+
+	if diffing && diffAgainst == "" {
+		return errors.New("flag 'against' is required, use a browser like --against firefox or a file written by --full")
+	}
+
+	if appendOutput && (outputFile == "" || (outputFormat != "netscape" && outputFormat != "jsonl" && !watch)) {
+		return errors.New("flag 'append' needs flag 'output' and --format netscape, --format jsonl or flag 'watch'")
+	}
+
+	if alsoPrint && !copyValue {
+		return errors.New("flag 'also-print' needs flag 'copy'")
+	}
+
+	if cookiejarGo && (requestCommand != "" || len(names) > 0) {
+		return errors.New("flag 'cookiejar-go' can't be combined with flag 'command' (or 'curl', 'wget') or flag 'name'")
+	}
+
+	for _, pattern := range excludeGlobs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q for flag 'exclude-profile-glob': %w", pattern, err)
+		}
+	}
+
+	for _, pattern := range nameGlobs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q for flag 'name-glob': %w", pattern, err)
+		}
+	}
+
+	if nameRegexExpr != "" {
+		compiled, err := regexp.Compile(nameRegexExpr)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression for flag 'name-regex': %w", err)
+		}
+		nameRegex = compiled
+	}
+
+	if excludeValueExpr != "" {
+		compiled, err := regexp.Compile(excludeValueExpr)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression for flag 'exclude-value-regex': %w", err)
+		}
+		excludeValueRegex = compiled
+	}
+
+	if err := validateExpiryFormat(expiryFormat); err != nil {
+		return err
+	}
+
+	if mergeStrategy != "" && mergeStrategy != "profile-unit" {
+		return errors.New("flag 'merge-strategy' must be empty or profile-unit")
+	}
+
+	switch dedupePolicy {
+	case "", "latest-expiry", "longest-path", "per-store":
+	default:
+		return errors.New("flag 'dedupe' must be one of latest-expiry, longest-path or per-store")
+	}
+
+	if dedupePolicy != "" && preferHttpOnly {
+		return errors.New("flag 'dedupe' and flag 'prefer-httponly' are mutually exclusive")
+	}
+
+	switch valueEncoding {
+	case "utf8", "latin1", "raw-base64":
+	default:
+		return errors.New("flag 'encoding' must be one of utf8, latin1 or raw-base64")
+	}
+
+	if limit < 0 {
+		return errors.New("flag 'limit' can't be negative")
+	}
+
+	switch groupBy {
+	case "", "store", "browser", "domain":
+	default:
+		return errors.New("flag 'group-by' must be one of store, browser or domain")
+	}
+
+	if groupBy != "" && (outputFormat != "json" || requestCommand != "" || len(names) > 0 || human || secretsDir != "" || cookiejarGo || setCookie || inventory || count) {
+		return errors.New("flag 'group-by' only applies to the JSON output, --full and --lengths")
+	}
+
+	switch keyring {
+	case "", "gnome", "kwallet", "basic", "none":
+	default:
+		return errors.New("flag 'keyring' must be one of " + cookielib.Keyrings)
+	}
+
+	if chromiumPassword == "" && keyring == "" {
+		chromiumPassword = os.Getenv("COOKIES_CHROMIUM_PASSWORD")
+	}
+
+	if chromiumPassword != "" && keyring != "" {
+		return errors.New("flag 'chromium-password' and flag 'keyring' are mutually exclusive")
+	}
+
+	if maxCookies < 0 {
+		return errors.New("flag 'max-cookies' can't be negative")
+	}
+
+	if retryOnEmpty < 0 {
+		return errors.New("flag 'retry-on-empty' can't be negative")
+	}
+
+	if watchInterval <= 0 {
+		return errors.New("flag 'watch-interval' must be positive")
+	}
+
+	if hashLength < 1 || hashLength > sha256.Size*2 {
+		return fmt.Errorf("flag 'hash-length' must be between 1 and %d", sha256.Size*2)
+	}
+
+	return nil
+}
+
+// otherOutputMode reports whether an output mode other than --format was selected
+func otherOutputMode() bool {
+	return requestCommand != "" || len(names) > 0 || human || secretsDir != "" || cookiejarGo || setCookie || inventory || count || valueLengths || fullCookieInfo
+}
+
+func isRequestCommand(tool string) bool {
+	for _, supported := range requestCommands {
+		if tool == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func isSupportedBrowser(browser string) bool {
+	for _, supported := range supportedBrowsers {
+		if browser == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// readsSeveralBrowsers reports whether the output has to tell the browsers of the cookies apart
+func readsSeveralBrowsers() bool {
+	return browser == "all" || strings.Contains(browser, ",")
+}
+
+// parseNameDomainPair splits name@domain at the last @, since domains can't contain one
+func parseNameDomainPair(pair string) (cookielib.NameDomain, error) {
+	i := strings.LastIndex(pair, "@")
+	if i <= 0 || i == len(pair)-1 {
+		return cookielib.NameDomain{}, fmt.Errorf("invalid value %q for flag 'name-domain', expected name@domain", pair)
+	}
+	return cookielib.NameDomain{Name: pair[:i], Domain: strings.TrimPrefix(pair[i+1:], ".")}, nil
+}
+
+// fetchOptions translates the filter and store flags into the options of the cookies package
+func fetchOptions(browser string, domain string) cookielib.Options {
+	opts := cookielib.Options{
+		Browser:             browser,
+		Domain:              domain,
+		ExactDomain:         exactDomain,
+		RegistrableDomain:   registrableDomain,
+		SuffixDomain:        domainMatch == "suffix",
+		RegexDomain:         domainMatch == "regex",
+		DomainGlob:          domainGlob,
+		NameDomains:         nameDomainPairs,
+		Names:               names,
+		NameGlobs:           nameGlobs,
+		NameRegex:           nameRegex,
+		Path:                pathPrefix,
+		SecureOnly:          secureOnly,
+		HttpOnly:            httpOnly,
+		Container:           container,
+		IncludeExpired:      showExpired,
+		ExpiresWithin:       expiresWithin,
+		ExpiresAfter:        expiresAfter,
+		Limit:               limit,
+		ExcludeSession:      excludeSession,
+		SessionOnly:         sessionOnly,
+		NonEmpty:            nonEmpty,
+		ValuePrefix:         valuePrefix,
+		ExcludeValue:        excludeValueRegex,
+		ThisSession:         thisSession,
+		NormalizeNames:      normalizeNames,
+		LowercaseNames:      lowercaseNames,
+		Profiles:            profiles,
+		ExcludeProfiles:     excludeProfiles,
+		ExcludeProfileGlobs: excludeGlobs,
+		PreferNewestStore:   preferNewestStore,
+		StoreFile:           storeFile,
+		StoreType:           storeType,
+		ChromiumPassword:    []byte(chromiumPassword),
+		Keyring:             keyring,
+		PerStoreTimeout:     perStoreTimeout,
+		FailFast:            failFast,
+		StoreError: func(err error) {
+			// Errors reading cookie stores are usually safe to ignore
+			// An example would be a non existant cookie store for an unused chrome profile
+			cookieStoreErrors = append(cookieStoreErrors, err)
+		},
+		Warn: func(message string) {
+			logWarning("%s", message)
+		},
+	}
+
+	if dumpRaw {
+		opts.RawCookies = dumpRawCookies
+	}
+
+	// an explicit --browser says more about the format than the file name,
+	// Electron apps use the Chromium store format even without it
+	if storeType == "" && (flagSet.Changed("browser") || browser == "electron") {
+		opts.StoreType = cookielib.BrowserStoreTypes[browser]
+	}
+
+	return opts
+}
+
+func getCookies(browser string, domain string) ([]*kooky.Cookie, error) {
+	fetched, err := cookielib.Fetch(fetchOptions(browser, domain))
+	if err != nil {
+		return nil, err
+	}
+
+	cookies := make([]*kooky.Cookie, 0, len(fetched))
+	for _, cookie := range fetched {
+		cookieSources[cookie.Cookie] = cookieSource{
+			Browser:  cookie.Browser,
+			Profile:  cookie.Profile,
+			FilePath: cookie.FilePath,
+		}
+		cookies = append(cookies, cookie.Cookie)
+	}
+
+	return cookies, nil
+}
+
+// sourcedCookies attaches the recorded stores to the cookies again, for the exports of the cookies package
+func sourcedCookies(cookies []*kooky.Cookie) []cookielib.Cookie {
+	sourced := make([]cookielib.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		source := cookieSources[cookie]
+		sourced = append(sourced, cookielib.Cookie{
+			Cookie:   cookie,
+			Browser:  source.Browser,
+			Profile:  source.Profile,
+			FilePath: source.FilePath,
+		})
+	}
+	return sourced
+}
+
+// cookieBrowser returns the browser a cookie was read from. Cookies that weren't read from
+// a store (e.g. from --from-json) are attributed to --browser, or "unknown" for several browsers.
+func cookieBrowser(cookie *kooky.Cookie) string {
+	if source, ok := cookieSources[cookie]; ok {
+		return source.Browser
+	}
+	if readsSeveralBrowsers() {
+		return "unknown"
+	}
+	return browser
+}
+
+// dumpMutex keeps the dumps of concurrently read stores from interleaving
+var dumpMutex sync.Mutex
+
+func dumpRawCookies(store kooky.CookieStore, cookies []*kooky.Cookie) {
+	dumpMutex.Lock()
+	defer dumpMutex.Unlock()
+
+	fmt.Fprintf(os.Stderr, "# %s %s (%s): %d cookies\n", store.Browser(), store.Profile(), store.FilePath(), len(cookies))
+	for _, cookie := range cookies {
+		if showValues {
+			fmt.Fprintf(os.Stderr, "%s\t%s\t%s\n", cookie.Domain, cookie.Name, cookie.Value)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s\t%s\n", cookie.Domain, cookie.Name)
+		}
+	}
+}
+
+// cookieKey is the name a cookie is keyed and matched by.
+// Normalization only applies to keys and matching, the cookie itself keeps its raw name.
+func cookieKey(cookie *kooky.Cookie) string {
+	return normalizeName(cookie.Name)
+}
+
+func normalizeName(cookieName string) string {
+	if !normalizeNames {
+		return cookieName
+	}
+	return cookielib.NormalizeName(cookieName, lowercaseNames)
+}
+
+// resolveDuplicatesPreferHttpOnly keeps one cookie per name, preferring HttpOnly cookies.
+// Between equal candidates the cookie read last wins, same as in the name keyed JSON output.
+func resolveDuplicatesPreferHttpOnly(cookies []*kooky.Cookie) []*kooky.Cookie {
+	var resolved []*kooky.Cookie
+	indexByName := make(map[string]int)
+
+	for _, cookie := range cookies {
+		i, ok := indexByName[cookieKey(cookie)]
+		if !ok {
+			indexByName[cookieKey(cookie)] = len(resolved)
+			resolved = append(resolved, cookie)
+			continue
+		}
+		if cookie.HttpOnly || !resolved[i].HttpOnly {
+			resolved[i] = cookie
+		}
+	}
+
+	return resolved
+}
+
+// hashCookieValues replaces every non empty value with a (truncated) SHA-256 hex digest,
+// so values can be compared between runs without being exposed
+func hashCookieValues(cookies []*kooky.Cookie) {
+	for _, cookie := range cookies {
+		if cookie.Value == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(cookie.Value))
+		cookie.Value = hex.EncodeToString(sum[:])[:hashLength]
+	}
+}
+
+// redactedPlaceholder replaces the values in the commands of --curl, which would fail with a masked value anyway
+const redactedPlaceholder = "REDACTED"
+
+// redactCookieValues masks the values for sharing the output, e.g. in bug reports
+func redactCookieValues(cookies []*kooky.Cookie) {
+	for _, cookie := range cookies {
+		if cookie.Value == "" {
+			continue
+		}
+		if requestCommand != "" {
+			cookie.Value = redactedPlaceholder
+		} else {
+			cookie.Value = redactValue(cookie.Value)
+		}
+	}
+}
+
+// redactValue keeps the first and last 4 characters and the length of value, e.g. abcd…wxyz(32).
+// Values of up to 12 characters would be mostly visible and are masked completely.
+func redactValue(value string) string {
+	runes := []rune(value)
+	if len(runes) == 0 {
+		return value
+	}
+	if len(runes) <= 12 {
+		return fmt.Sprintf("…(%d)", len(runes))
+	}
+	return fmt.Sprintf("%s…%s(%d)", string(runes[:4]), string(runes[len(runes)-4:]), len(runes))
+}
+
+// marshalJson is used by all JSON outputs so they honor --canonical
+func marshalJson(v interface{}) ([]byte, error) {
+	if pretty {
+		return json.MarshalIndent(v, "", "  ")
+	}
+
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if canonical {
+		return canonicalizeJson(jsonBytes)
+	}
+
+	return jsonBytes, nil
+}
+
+// encodeCookieValues converts the raw value bytes according to --encoding, so values that aren't
+// valid UTF-8 don't end up as replacement characters in the JSON output
+func encodeCookieValues(cookies []*kooky.Cookie) {
+	for _, cookie := range cookies {
+		switch valueEncoding {
+		case "latin1":
+			// every ISO-8859-1 byte is the unicode code point of the same value
+			runes := make([]rune, len(cookie.Value))
+			for i := 0; i < len(cookie.Value); i++ {
+				runes[i] = rune(cookie.Value[i])
+			}
+			cookie.Value = string(runes)
+		case "raw-base64":
+			cookie.Value = base64.StdEncoding.EncodeToString([]byte(cookie.Value))
+		}
+	}
+}
+
+// wrapInRootKey nests v under --root-key, if one was given
+func wrapInRootKey(v interface{}) interface{} {
+	if rootKey == "" {
+		return v
+	}
+	return map[string]interface{}{rootKey: v}
+}
+
+// outputKeys returns the JSON key of every cookie: its name, or name@domain/path if cookies
+// on different domains or paths share the name, so none of them is silently dropped.
+// Cookies with the same name, domain and path (e.g. from several profiles) still share a key.
+func outputKeys(cookies []*kooky.Cookie) map[*kooky.Cookie]string {
+	locations := make(map[string]map[string]bool)
+	for _, cookie := range cookies {
+		if locations[cookieKey(cookie)] == nil {
+			locations[cookieKey(cookie)] = make(map[string]bool)
+		}
+		locations[cookieKey(cookie)][cookieLocation(cookie)] = true
+	}
+
+	keys := make(map[*kooky.Cookie]string, len(cookies))
+	for _, cookie := range cookies {
+		if len(locations[cookieKey(cookie)]) > 1 {
+			keys[cookie] = cookieKey(cookie) + "@" + cookieLocation(cookie)
+		} else {
+			keys[cookie] = cookieKey(cookie)
+		}
+	}
+	return keys
+}
+
+func cookieLocation(cookie *kooky.Cookie) string {
+	return cookie.Domain + cookie.Path
+}
+
+// outputGroup returns the group of a cookie in the JSON output, nil if the output isn't nested.
+// Without --group-by the cookies of several browsers are nested under the browser,
+// as the same name can exist in several browsers.
+func outputGroup() func(cookie *kooky.Cookie) string {
+	switch {
+	case groupBy == "store":
+		return func(cookie *kooky.Cookie) string {
+			if source, ok := cookieSources[cookie]; ok {
+				return source.FilePath
+			}
+			return "unknown"
+		}
+	case groupBy == "domain":
+		return func(cookie *kooky.Cookie) string {
+			return strings.TrimPrefix(cookie.Domain, ".")
+		}
+	case groupBy == "browser", readsSeveralBrowsers():
+		return cookieBrowser
+	}
+	return nil
+}
+
+// keyByName maps the cookie keys to entry, nested under the group of the cookies if the output is grouped
+func keyByName(cookies []*kooky.Cookie, entry func(cookie *kooky.Cookie) interface{}) interface{} {
+	keys := outputKeys(cookies)
+	group := outputGroup()
+	if group == nil {
+		cookiesMap := make(map[string]interface{}, len(cookies))
+		for _, item := range cookies {
+			cookiesMap[keys[item]] = entry(item)
+		}
+		return cookiesMap
+	}
+
+	groupsMap := make(map[string]map[string]interface{})
+	for _, item := range cookies {
+		cookieGroup := group(item)
+		if groupsMap[cookieGroup] == nil {
+			groupsMap[cookieGroup] = make(map[string]interface{})
+		}
+		groupsMap[cookieGroup][keys[item]] = entry(item)
+	}
+	return groupsMap
+}
+
+func serializeCookiesToJson(cookies []*kooky.Cookie) (string, error) {
+	cookiesMap := keyByName(cookies, func(cookie *kooky.Cookie) interface{} {
+		return cookie.Value
+	})
+
+	cookiesJsonBytes, err := marshalJson(wrapInRootKey(cookiesMap))
+	if err != nil {
+		return "", err
+	}
+
+	return string(cookiesJsonBytes), nil
+}
+
+func serializeValueLengthsToJson(cookies []*kooky.Cookie) (string, error) {
+	lengthsMap := keyByName(cookies, func(cookie *kooky.Cookie) interface{} {
+		return len(cookie.Value)
+	})
+
+	lengthsJsonBytes, err := marshalJson(wrapInRootKey(lengthsMap))
+	if err != nil {
+		return "", err
+	}
+
+	return string(lengthsJsonBytes), nil
+}
+
+// sortedCookies returns a copy of cookies ordered by name, domain and path, so the output is stable between runs
+func sortedCookies(cookies []*kooky.Cookie) []*kooky.Cookie {
+	sorted := make([]*kooky.Cookie, len(cookies))
+	copy(sorted, cookies)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if cookieKey(sorted[i]) != cookieKey(sorted[j]) {
+			return cookieKey(sorted[i]) < cookieKey(sorted[j])
+		}
+		if sorted[i].Domain != sorted[j].Domain {
+			return sorted[i].Domain < sorted[j].Domain
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+	return sorted
+}
+
+type cookieCounts struct {
+	Total    int            `json:"total"`
+	Browsers map[string]int `json:"browsers"`
+	Domains  map[string]int `json:"domains"`
+}
+
+func serializeCountsToJson(cookies []*kooky.Cookie) (string, error) {
+	counts := cookieCounts{
+		Total:    len(cookies),
+		Browsers: make(map[string]int),
+		Domains:  make(map[string]int),
+	}
+	for _, item := range cookies {
+		counts.Browsers[cookieBrowser(item)]++
+		counts.Domains[item.Domain]++
+	}
+
+	countsJsonBytes, err := marshalJson(wrapInRootKey(counts))
+	if err != nil {
+		return "", err
+	}
+
+	return string(countsJsonBytes), nil
+}
+
+type domainInventory struct {
+	Count    int `json:"count"`
+	Secure   int `json:"secure"`
+	HttpOnly int `json:"httponly"`
+	Session  int `json:"session"`
+}
+
+func serializeInventoryToJson(cookies []*kooky.Cookie) (string, error) {
+	inventoryMap := make(map[string]*domainInventory)
+
+	for _, item := range cookies {
+		entry, ok := inventoryMap[item.Domain]
+		if !ok {
+			entry = &domainInventory{}
+			inventoryMap[item.Domain] = entry
+		}
+		entry.Count++
+		if item.Secure {
+			entry.Secure++
+		}
+		if item.HttpOnly {
+			entry.HttpOnly++
+		}
+		if cookielib.IsSession(item) {
+			entry.Session++
+		}
+	}
+
+	inventoryJsonBytes, err := marshalJson(wrapInRootKey(inventoryMap))
+	if err != nil {
+		return "", err
+	}
+
+	return string(inventoryJsonBytes), nil
+}
 
-	if help || pflag.NFlag() == 0 {
-		printUsage()
+// serializeFullCookieInfoToJson outputs an array instead of a name keyed map,
+// so cookies sharing a name don't overwrite each other
+func serializeFullCookieInfoToJson(cookies []*kooky.Cookie) (string, error) {
+	var full interface{}
+	// the cookies of several browsers stay a single list, they have a Browser field
+	if groupBy != "" {
+		group := outputGroup()
+		groups := make(map[string][]fullCookie)
+		for _, item := range sortedCookies(cookies) {
+			groups[group(item)] = append(groups[group(item)], fullCookieFields(item))
+		}
+		full = groups
+	} else {
+		cookieList := make([]fullCookie, 0, len(cookies))
+		for _, item := range sortedCookies(cookies) {
+			cookieList = append(cookieList, fullCookieFields(item))
+		}
+		full = cookieList
 	}
 
-	if domain == "" {
-		return errors.New("flag domain is required, use either -d $DOMAIN or --domain $DOMAIN")
+	cookiesJsonBytes, err := marshalJson(wrapInRootKey(full))
+	if err != nil {
+		return "", err
 	}
 
-	if curl && name != "" {
-		return errors.New("flag 'curl' and flag 'name' are mutually exclusive")
-	}
+	return string(cookiesJsonBytes), nil
+}
 
-	return nil
+// fullCookie is a single cookie of --full. The fields are in alphabetical order, which is the
+// order of the map based output of earlier versions, and --from-json reads the same shape.
+type fullCookie struct {
+	// Browser, Profile and Store are the store the cookie was read from. --from-json keeps them,
+	// cookies of files without a Store only get a Browser if several browsers are read.
+	Browser string `json:"Browser,omitempty"`
+	// Container is only used by Firefox
+	Container        *string        `json:"Container,omitempty"`
+	Cookie           fullHttpCookie `json:"Cookie"`
+	Creation         interface{}    `json:"Creation"`
+	DecryptionStatus string         `json:"DecryptionStatus"`
+	// ExpiresIn and ExpiresUnix are the remaining lifetime and the Unix time of the expiry, both
+	// missing for session cookies. ExpiresIn is "expired" once the expiry has passed.
+	ExpiresIn   string `json:"ExpiresIn,omitempty"`
+	ExpiresUnix *int64 `json:"ExpiresUnix,omitempty"`
+	// MaxAge is only set with --max-age, session cookies have no expiry and therefore no Max-Age
+	MaxAge  *int64  `json:"MaxAge,omitempty"`
+	Profile *string `json:"Profile,omitempty"`
+	Session bool    `json:"Session"`
+	Store   string  `json:"Store,omitempty"`
 }
 
-func getCookies(browser string, domain string) ([]*kooky.Cookie, error) {
-	var cookies []*kooky.Cookie
-	cookieStores := kooky.FindAllCookieStores()
+// fullHttpCookie has the fields of http.Cookie, with the time formatted by --expiry-format
+type fullHttpCookie struct {
+	Domain      string        `json:"Domain"`
+	Expires     interface{}   `json:"Expires"`
+	HttpOnly    bool          `json:"HttpOnly"`
+	MaxAge      int           `json:"MaxAge"`
+	Name        string        `json:"Name"`
+	Partitioned bool          `json:"Partitioned"`
+	Path        string        `json:"Path"`
+	Quoted      bool          `json:"Quoted"`
+	Raw         string        `json:"Raw"`
+	RawExpires  string        `json:"RawExpires"`
+	SameSite    http.SameSite `json:"SameSite"`
+	Secure      bool          `json:"Secure"`
+	Unparsed    []string      `json:"Unparsed"`
+	Value       string        `json:"Value"`
+}
 
-	for _, store := range cookieStores {
-		defer store.Close()
+// fullCookieFields describes a single cookie for --full
+func fullCookieFields(item *kooky.Cookie) fullCookie {
+	full := fullCookie{
+		Cookie: fullHttpCookie{
+			Domain:      item.Domain,
+			Expires:     formatTime(item.Expires),
+			HttpOnly:    item.HttpOnly,
+			MaxAge:      item.MaxAge,
+			Name:        item.Name,
+			Partitioned: item.Partitioned,
+			Path:        item.Path,
+			Quoted:      item.Quoted,
+			Raw:         item.Raw,
+			RawExpires:  item.RawExpires,
+			SameSite:    item.SameSite,
+			Secure:      item.Secure,
+			Unparsed:    item.Unparsed,
+			Value:       item.Value,
+		},
+		Creation:         formatTime(item.Creation),
+		DecryptionStatus: decryptionStatus(item),
+		Session:          cookielib.IsSession(item),
+	}
 
-		if store.Browser() != browser {
-			continue
-		}
+	if !cookielib.IsSession(item) {
+		full.ExpiresIn = strings.TrimPrefix(describeExpiry(item), "expires in ")
+		expiresUnix := item.Expires.Unix()
+		full.ExpiresUnix = &expiresUnix
+	}
+	if cookieBrowser(item) == "firefox" {
+		container := item.Container
+		full.Container = &container
+	}
+	if maxAge && !cookielib.IsSession(item) {
+		cookieMaxAge := cookieMaxAge(item)
+		full.MaxAge = &cookieMaxAge
+	}
+	if source, ok := cookieSources[item]; ok {
+		full.Browser = source.Browser
+		profile := source.Profile
+		full.Profile = &profile
+		full.Store = source.FilePath
+	} else if readsSeveralBrowsers() {
+		full.Browser = cookieBrowser(item)
+	}
+	return full
+}
 
-		var filters []kooky.Filter
-		// only append the Valid filter if showExpired is false (default)
-		if !showExpired {
-			filters = append(filters, kooky.Valid)
-		}
+// decryptionStatus tells a genuinely empty value apart from a successfully read one.
+// kooky aborts reading a store when a value can't be decrypted, so undecryptable cookies never
+// get here, they show up as store error instead.
+func decryptionStatus(cookie *kooky.Cookie) string {
+	if cookie.Value == "" {
+		return "empty"
+	}
+	return "ok"
+}
 
-		filters = append(filters, kooky.DomainContains(domain))
+// expiryFormatPresets maps the --expiry-format presets to layouts, rfc3339 and unix are handled in formatTime
+var expiryFormatPresets = map[string]string{
+	"iso-local": time.RFC3339,
+	"rfc1123":   time.RFC1123,
+}
 
-		// Errors reading cookie stores are usually safe to ignore
-		// An example would be a non existant cookie store for an unused chrome profile
-		storeCookies, err := store.ReadCookies(filters...)
-		if err != nil {
-			cookieStoreErrors = append(cookieStoreErrors, err.Error())
+// formatTime renders times according to --expiry-format and --local-time.
+// The default rfc3339 keeps the time.Time as is, so the output is the same as the JSON encoding of it.
+func formatTime(t time.Time) interface{} {
+	switch expiryFormat {
+	case "rfc3339":
+		return displayTime(t)
+	case "unix":
+		if t.IsZero() {
+			return 0
 		}
+		return t.Unix()
+	}
 
-		cookies = append(cookies, storeCookies...)
+	if t.IsZero() {
+		return ""
 	}
 
-	if cookies == nil {
-		return nil, errors.New("no cookies for browser " + browser + " and domain " + domain + " found.")
+	if expiryFormat == "iso-local" {
+		return t.Local().Format(time.RFC3339)
 	}
 
-	return cookies, nil
+	layout, ok := expiryFormatPresets[expiryFormat]
+	if !ok {
+		layout = expiryFormat
+	}
+	return displayTime(t).Format(layout)
 }
 
-func serializeCookiesToJson(cookies []*kooky.Cookie) (string, error) {
-	cookiesMap := make(map[string]string, len(cookies))
+// validateExpiryFormat rejects layouts without any time elements, which would print the layout itself
+func validateExpiryFormat(format string) error {
+	if format == "rfc3339" || format == "unix" {
+		return nil
+	}
+	if _, ok := expiryFormatPresets[format]; ok {
+		return nil
+	}
 
-	for _, item := range cookies {
-		cookiesMap[item.Name] = item.Value
+	sample := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	if format == "" || sample.Format(format) == format {
+		return fmt.Errorf("invalid value %q for flag 'expiry-format', use rfc3339, unix, iso-local, rfc1123 or a Go time layout", format)
 	}
+	return nil
+}
 
-	cookiesJsonBytes, err := json.Marshal(cookiesMap)
-	if err != nil {
-		return "", err
+// displayTime converts t to UTC, or to the local timezone with --local-time
+func displayTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
 	}
+	if localTime {
+		return t.Local()
+	}
+	return t.UTC()
+}
 
-	return string(cookiesJsonBytes), nil
+// cookieMaxAge returns the seconds until the cookie expires, clamped at 0 for expired cookies
+func cookieMaxAge(cookie *kooky.Cookie) int64 {
+	seconds := int64(time.Until(cookie.Expires).Seconds())
+	if seconds < 0 {
+		return 0
+	}
+	return seconds
 }
 
-func serializeFullCookieInfoToJson(cookies []*kooky.Cookie) (string, error) {
-	cookiesMap := make(map[string]map[string]interface{})
+// requestScheme uses --scheme if given, otherwise https when every cookie is Secure and http if any isn't
+func requestScheme(cookies []*kooky.Cookie) string {
+	if scheme != "" {
+		return scheme
+	}
 
-	for _, item := range cookies {
-		cookieMap := make(map[string]interface{})
-		v := reflect.ValueOf(item).Elem()
-		t := v.Type()
-
-		for i := 0; i < v.NumField(); i++ {
-			field := t.Field(i)
-			value := v.Field(i).Interface()
-			// container for cookies are only used by firefox
-			if field.Name == "Container" && browser != "firefox" {
-				continue
+	for _, cookie := range cookies {
+		if !cookie.Secure {
+			return "http"
+		}
+	}
+	return "https"
+}
+
+// createSetCookieHeaders prints the cookies as a server would have set them. Session cookies
+// have a zero expiry, which http.Cookie leaves out, so they stay session cookies.
+func createSetCookieHeaders(cookies []*kooky.Cookie) string {
+	var lines []string
+	for _, cookie := range cookies {
+		httpCookie := cookie.Cookie
+		if maxAge && !cookielib.IsSession(cookie) {
+			httpCookie.MaxAge = int(cookieMaxAge(cookie))
+			// a Max-Age of 0 means no attribute to http.Cookie, -1 writes Max-Age=0
+			if httpCookie.MaxAge == 0 {
+				httpCookie.MaxAge = -1
 			}
+		}
 
-			cookieMap[field.Name] = value
+		header := httpCookie.String()
+		if header == "" {
+			logWarning("skipping cookie %q of %s, it can't be written as Set-Cookie header", cookie.Name, cookie.Domain)
+			continue
 		}
-		cookiesMap[item.Name] = cookieMap
+		lines = append(lines, "Set-Cookie: "+header)
 	}
-	cookiesJsonBytes, err := json.Marshal(cookiesMap)
-	if err != nil {
-		return "", err
+	return strings.Join(lines, "\n")
+}
+
+// cookieURL returns the URL a cookie would be sent to, which is what http.CookieJar.SetCookies expects
+func cookieURL(cookie *kooky.Cookie) string {
+	scheme := "http"
+	if cookie.Secure {
+		scheme = "https"
 	}
 
-	return string(cookiesJsonBytes), nil
-}
+	cookiePath := cookie.Path
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
 
-func createCurlCommand(cookies []*kooky.Cookie, domain string) string {
-	var cookieParts []string
+	return scheme + "://" + strings.TrimPrefix(cookie.Domain, ".") + cookiePath
+}
 
+func createCookiejarGoSource(cookies []*kooky.Cookie) string {
+	cookiesByURL := make(map[string][]*kooky.Cookie)
+	var urls []string
 	for _, cookie := range cookies {
-		cookieParts = append(cookieParts, fmt.Sprintf("%s=%s", cookie.Name, cookie.Value))
+		u := cookieURL(cookie)
+		if _, ok := cookiesByURL[u]; !ok {
+			urls = append(urls, u)
+		}
+		cookiesByURL[u] = append(cookiesByURL[u], cookie)
+	}
+	sort.Strings(urls)
+
+	var b strings.Builder
+	b.WriteString("// for rawURL, cookies := range cookiesByURL {\n")
+	b.WriteString("// \tu, _ := url.Parse(rawURL)\n")
+	b.WriteString("// \tjar.SetCookies(u, cookies)\n")
+	b.WriteString("// }\n")
+	b.WriteString("cookiesByURL := map[string][]*http.Cookie{\n")
+	for _, u := range urls {
+		fmt.Fprintf(&b, "\t%q: {\n", u)
+		for _, cookie := range cookiesByURL[u] {
+			fmt.Fprintf(&b, "\t\t{Name: %q, Value: %q, Path: %q", cookie.Name, cookie.Value, cookie.Path)
+			// host-only cookies are stored without a leading dot and must not set Domain,
+			// otherwise the jar would also send them to subdomains
+			if strings.HasPrefix(cookie.Domain, ".") {
+				fmt.Fprintf(&b, ", Domain: %q", strings.TrimPrefix(cookie.Domain, "."))
+			}
+			if !cookie.Expires.IsZero() {
+				fmt.Fprintf(&b, ", Expires: time.Unix(%d, 0)", cookie.Expires.Unix())
+			}
+			if cookie.Secure {
+				b.WriteString(", Secure: true")
+			}
+			if cookie.HttpOnly {
+				b.WriteString(", HttpOnly: true")
+			}
+			b.WriteString("},\n")
+		}
+		b.WriteString("\t},\n")
 	}
+	b.WriteString("}")
 
-	cookieString := strings.Join(cookieParts, ";")
+	return b.String()
+}
 
-	return fmt.Sprintf("curl -H 'Cookie: %s' 'https://%s'", cookieString, domain)
+// describeCookieSchema lists the fields of the cookie struct, fields of embedded structs are
+// promoted just like Go does, so the names can be used as is
+func describeCookieSchema(t reflect.Type) string {
+	var lines []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			lines = append(lines, describeCookieSchema(field.Type))
+			continue
+		}
+		lines = append(lines, field.Name+"\t"+field.Type.String())
+	}
+	return strings.Join(lines, "\n")
 }
 
 func getCookieValue(cookies []*kooky.Cookie, name string) (string, error) {
+	cookie, err := findCookie(cookies, name)
+	if err != nil {
+		return "", err
+	}
+	if cookie.Value == "" {
+		return "", errors.New("cookie exists but has an empty value")
+	}
+	return cookie.Value, nil
+}
+
+// findCookie returns the cookie with the given name. Several cookies with the same value
+// (e.g. from several profiles) are fine, differing values are reported as ambiguous.
+func findCookie(cookies []*kooky.Cookie, name string) (*kooky.Cookie, error) {
+	var found *kooky.Cookie
+	var locations []string
+	ambiguous := false
 	for _, cookie := range cookies {
-		if name == cookie.Name {
-			if cookie.Value == "" {
-				return "", errors.New("cookie exists but has an empty value")
-			}
-			return cookie.Value, nil
+		if normalizeName(name) != cookieKey(cookie) {
+			continue
+		}
+		if found != nil && cookie.Value != found.Value {
+			ambiguous = true
 		}
+		if found == nil {
+			found = cookie
+		}
+		locations = append(locations, cookieLocation(cookie))
+	}
+
+	if found == nil {
+		return nil, errCookieNotExists
+	}
+	if ambiguous {
+		return nil, fmt.Errorf("%w, it exists with different values for %s, narrow it down with --exact-domain or --path", errCookieAmbiguous, strings.Join(locations, ", "))
 	}
-	return "", errors.New("cookie does not exist")
+	return found, nil
 }
 
-func formatStoreErrorsAsJson() (string, error) {
-	jsonErrors := make(map[string]string, len(cookieStoreErrors))
-	for i, v := range cookieStoreErrors {
-		key := strconv.Itoa(i + 1)
-		jsonErrors[key] = v
+// serializeNamedValuesToJson maps each requested name to the value of its cookie,
+// or null if it doesn't exist, so one missing cookie doesn't fail the others
+func serializeNamedValuesToJson(cookies []*kooky.Cookie, names []string) (string, error) {
+	valuesMap := make(map[string]*string, len(names))
+	for _, name := range names {
+		cookie, err := findCookie(cookies, name)
+		if errors.Is(err, errCookieNotExists) {
+			valuesMap[name] = nil
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("cookie %s: %w", name, err)
+		}
+		valuesMap[name] = &cookie.Value
 	}
 
-	jsonErrorsString, err := json.Marshal(jsonErrors)
+	valuesJsonBytes, err := marshalJson(wrapInRootKey(valuesMap))
 	if err != nil {
 		return "", err
 	}
 
-	return string(jsonErrorsString), nil
+	return string(valuesJsonBytes), nil
 }
 
 func run() error {
+	// the completion scripts run this on every tab, it must not print usage or diagnostics
+	if len(os.Args) > 1 && os.Args[1] == completeCommand {
+		return printCompletions(os.Stdout, os.Args[2:])
+	}
+
 	err := parseFlags()
 	if err != nil {
-		return fmt.Errorf("incorrect flag usage: %w", err)
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	if printingCompletion {
+		return printCompletionScript(completionShell)
+	}
+
+	if serveAddress != "" {
+		return serve(serveAddress)
+	}
+
+	if diagnosePerms {
+		permissionsJson, err := marshalJson(diagnoseStorePermissions())
+		if err != nil {
+			return fmt.Errorf("failed to create JSON: %w", err)
+		}
+		return writeOutput(string(permissionsJson))
+	}
+
+	if printSchema {
+		return writeOutput(describeCookieSchema(reflect.TypeOf(kooky.Cookie{})))
+	}
+
+	if listStoresJson && storesTable {
+		return writeOutput(createStoresTable(cookielib.ListStores()))
+	}
+
+	if listStoresJson {
+		storesJson, err := marshalJson(cookielib.ListStores())
+		if err != nil {
+			return fmt.Errorf("failed to create JSON: %w", err)
+		}
+		return writeOutput(string(storesJson))
+	}
+
+	if fromJson != "" {
+		cookies, err := readCookiesFromJsonFile(fromJson)
+		if err != nil {
+			return fmt.Errorf("failed to read cookies from %s: %w", fromJson, err)
+		}
+		filters, err := fetchOptions(browser, domain).Filters()
+		if err != nil {
+			return fmt.Errorf("failed to obtain cookies: %w", err)
+		}
+		cookies = kooky.FilterCookies(cookies, filters...)
+		if limit > 0 && len(cookies) > limit {
+			cookies = cookies[:limit]
+		}
+		if len(cookies) == 0 {
+			err = fmt.Errorf("%w in %s for domain %s", errNoCookies, fromJson, domain)
+		}
+		return outputFoundCookies(cookies, err)
+	}
+
+	if browser == "recent" && storeFile == "" {
+		recent, err := cookielib.MostRecentBrowser()
+		if err != nil {
+			return fmt.Errorf("failed to detect the most recently used browser: %w", err)
+		}
+		logDiagnostic("info", fmt.Sprintf("using %s, the most recently used browser", recent), "")
+		browser = recent
+	}
+
+	if watch {
+		return watchCookies(browser, domain)
+	}
+
+	if diffing {
+		return diffCookies(browser, domain)
 	}
 
 	cookies, err := getCookies(browser, domain)
+	// only an empty result is retried, any other error is final
+	for attempt := 0; attempt < retryOnEmpty && errors.Is(err, errNoCookies); attempt++ {
+		time.Sleep(retryDelay)
+		cookieStoreErrors = nil
+		cookies, err = getCookies(browser, domain)
+	}
+	logStoreErrors()
+	return outputFoundCookies(cookies, err)
+}
+
+// outputFoundCookies prints the result of reading the cookies, from the stores or --from-json.
+// Finding no cookies is only an error if the options don't expect that outcome.
+func outputFoundCookies(cookies []*kooky.Cookie, err error) error {
+	// an empty result is a valid outcome when asserting on the count
+	if errors.Is(err, errNoCookies) && (expectCount == 0 || expectMinCount == 0) {
+		cookies, err = nil, nil
+	}
+	if errors.Is(err, errNoCookies) && len(names) > 0 && nullIfMissing {
+		if len(names) == 1 {
+			return writeOutput("null")
+		}
+		cookies, err = nil, nil
+	}
+	// only the named cookies are read, so none matching means they don't exist
+	if errors.Is(err, errNoCookies) && len(names) > 0 {
+		cookies, err = nil, nil
+	}
 	if err != nil {
+		printSummary("error", 0)
 		return fmt.Errorf("failed to obtain cookies: %w", err)
 	}
-	if debug {
-		jsonCookieStoreErrors, err := formatStoreErrorsAsJson()
+
+	return outputCookies(cookies)
+}
+
+// transformCookieValues applies --trim, --strip-prefix, --hash-values and --encoding to the values.
+// --redact isn't part of it, the redacted values are only for printing.
+func transformCookieValues(cookies []*kooky.Cookie) {
+	// trimming happens first, so hashes and the curl Cookie header use the cleaned values
+	if trimValues {
+		for _, cookie := range cookies {
+			cookie.Value = strings.TrimSpace(cookie.Value)
+		}
+	}
+
+	if stripPrefix != "" {
+		for _, cookie := range cookies {
+			cookie.Value = strings.TrimPrefix(cookie.Value, stripPrefix)
+		}
+	}
+
+	if hashValues {
+		hashCookieValues(cookies)
+	}
+
+	encodeCookieValues(cookies)
+}
+
+// outputCookies post-processes the cookies and prints them in the requested output mode
+func outputCookies(cookies []*kooky.Cookie) error {
+	if mergeStrategy == "profile-unit" {
+		cookies = selectProfileUnit(cookies)
+	}
+
+	if pickDomains {
+		picked, err := pickDomain(cookies)
 		if err != nil {
-			return fmt.Errorf("failed to marshal errors to json: %w", err)
+			return fmt.Errorf("failed to pick a domain: %w", err)
 		}
-		fmt.Println(jsonCookieStoreErrors)
+		cookies = picked
+	}
+
+	if preferHttpOnly {
+		cookies = resolveDuplicatesPreferHttpOnly(cookies)
+	}
+
+	if dedupePolicy != "" {
+		cookies = dedupeCookies(cookies, dedupePolicy)
+	}
+
+	if expectCount >= 0 && len(cookies) != expectCount {
+		return fmt.Errorf("expected %d cookies, but %d match", expectCount, len(cookies))
+	}
+	if expectMinCount >= 0 && len(cookies) < expectMinCount {
+		return fmt.Errorf("expected at least %d cookies, but %d match", expectMinCount, len(cookies))
+	}
+
+	if maxCookies > 0 && len(cookies) > maxCookies {
+		return fmt.Errorf("%d cookies match, more than the %d allowed by --max-cookies, use a tighter filter", len(cookies), maxCookies)
+	}
+
+	transformCookieValues(cookies)
+
+	// after encoding, so the visible characters are those of the printed value
+	if redact {
+		redactCookieValues(cookies)
 	}
 
-	if name != "" {
-		cookie_value, err := getCookieValue(cookies, name)
+	var output string
+	if len(names) == 1 {
+		cookie_value, err := getCookieValue(cookies, names[0])
+		missing := errors.Is(err, errCookieNotExists)
+		if missing && nullIfMissing {
+			cookie_value, err = "null", nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get value for cookie %s: %w", names[0], err)
+		}
+		output = cookie_value
+
+		// the value stays out of the terminal scrollback unless it's printed explicitly
+		if copyValue && !missing {
+			if err := copyToClipboard(cookie_value); err != nil {
+				return fmt.Errorf("failed to copy value of cookie %s: %w", names[0], err)
+			}
+			if !alsoPrint {
+				return nil
+			}
+		}
+
+	} else if len(names) > 1 {
+		valuesJson, err := serializeNamedValuesToJson(cookies, names)
+		if err != nil {
+			return fmt.Errorf("failed to get values for cookies: %w", err)
+		}
+		output = valuesJson
+
+	} else if requestCommand != "" {
+		output = createRequestCommand(requestCommand, cookies)
+
+	} else if human {
+		output = createHumanSummary(cookies)
+
+	} else if browse {
+		return browseCookies(cookies)
+
+	} else if secretsDir != "" {
+		if err := writeSecretsDir(secretsDir, cookies); err != nil {
+			return fmt.Errorf("failed to write cookies to %s: %w", secretsDir, err)
+		}
+		printSummary("ok", len(cookies))
+		return nil
+
+	} else if setCookie {
+		output = createSetCookieHeaders(cookies)
+
+	} else if cookiejarGo {
+		output = createCookiejarGoSource(cookies)
+
+	} else if outputFormat == "template" {
+		templateOutput, err := executeOutputTemplate(outputTemplate, cookies)
+		if err != nil {
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+		output = templateOutput
+
+	} else if outputFormat == "header" {
+		output = cookielib.CookieHeader(sourcedCookies(cookies))
+
+	} else if outputFormat == "gojar" {
+		jar, err := cookiesjar.FromCookies(sourcedCookies(cookies))
 		if err != nil {
-			return fmt.Errorf("failed to get value for cookie %s: %w", name, err)
+			return fmt.Errorf("failed to create cookie jar: %w", err)
 		}
-		fmt.Println(cookie_value)
+		// the entries are what jar.Write writes, but formatted by --pretty and --canonical
+		jarJson, err := marshalJson(jar.Entries())
+		if err != nil {
+			return fmt.Errorf("failed to write cookie jar: %w", err)
+		}
+		output = string(jarJson)
+
+	} else if outputFormat == "har" {
+		harJson, err := serializeCookiesToHar(cookies)
+		if err != nil {
+			return fmt.Errorf("failed to create HAR: %w", err)
+		}
+		output = harJson
 
-	} else if curl {
-		fmt.Println(
-			createCurlCommand(cookies, domain),
-		)
+	} else if outputFormat != "json" {
+		var exported strings.Builder
+		if err := cookielib.Export(&exported, sourcedCookies(cookies), cookielib.Format(outputFormat)); err != nil {
+			return fmt.Errorf("failed to export cookies as %s: %w", outputFormat, err)
+		}
+		output = exported.String()
 
+	} else if count {
+		countsJson, err := serializeCountsToJson(cookies)
+		if err != nil {
+			return fmt.Errorf("failed to create JSON: %w", err)
+		}
+		output = countsJson
+	} else if inventory {
+		inventoryJson, err := serializeInventoryToJson(cookies)
+		if err != nil {
+			return fmt.Errorf("failed to create JSON: %w", err)
+		}
+		output = inventoryJson
+	} else if valueLengths {
+		cookieJson, err := serializeValueLengthsToJson(cookies)
+		if err != nil {
+			return fmt.Errorf("failed to create JSON: %w", err)
+		}
+		output = cookieJson
 	} else if fullCookieInfo {
 		cookieJson, err := serializeFullCookieInfoToJson(cookies)
 		if err != nil {
 			return fmt.Errorf("failed to create JSON: %w", err)
 		}
-		fmt.Println(cookieJson)
+		output = cookieJson
 	} else {
 		cookieJson, err := serializeCookiesToJson(cookies)
 		if err != nil {
 			return fmt.Errorf("failed to create JSON: %w", err)
 		}
-		fmt.Println(cookieJson)
+		output = cookieJson
+	}
+
+	if encryptSnapshot {
+		sealed, err := sealSnapshot(output)
+		if err != nil {
+			return err
+		}
+		output = sealed
+	}
+
+	if err := writeOutput(output); err != nil {
+		return err
+	}
+
+	printSummary("ok", len(cookies))
+	return nil
+}
+
+// createStoresTable lists the stores in aligned columns for reading them in a terminal
+func createStoresTable(listings []cookielib.StoreListing) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BROWSER\tPROFILE\tDIRECTORY\tDEFAULT\tREADABLE\tLOCKED\tMODIFIED\tPATH")
+	for _, listing := range listings {
+		modified := "-"
+		if listing.Mtime != nil {
+			modified = displayTime(*listing.Mtime).Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", listing.Browser, listing.Profile, listing.Directory,
+			yesNo(listing.Default), yesNo(listing.Readable), yesNo(listing.Locked), modified, listing.Path)
+	}
+	w.Flush()
+	return b.String()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// writeOutput prints the result to stdout, or writes it to --output with 0600 permissions
+func writeOutput(output string) error {
+	if !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+
+	if outputFile == "" {
+		_, err := fmt.Print(output)
+		return err
+	}
+
+	if appendOutput {
+		return appendOutputFile(output)
+	}
+
+	if err := replaceFile(outputFile, output); err != nil {
+		return fmt.Errorf("failed to write output file %s: %w", outputFile, err)
 	}
+
 	return nil
 }
 
+// replaceFile is used by everything that writes cookies to a file. The content goes to a
+// temporary file next to filename, which is renamed over it, so readers never see a partial
+// file, a symlink at filename isn't followed and an existing file doesn't keep wider
+// permissions. CreateTemp creates the file with 0600.
+func replaceFile(filename string, content string) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
+
+// netscapeHeader is the first line cookielib.Export writes for the netscape format
+const netscapeHeader = "# Netscape HTTP Cookie File\n"
+
+// appendOutputFile adds the output to the end of the output file. The netscape header is
+// only written to a new file, so the result is still a single valid cookies.txt.
+func appendOutputFile(output string) error {
+	file, err := openOutputFile()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		output = strings.TrimPrefix(output, netscapeHeader)
+	}
+
+	if _, err := file.WriteString(output); err != nil {
+		return fmt.Errorf("failed to write output file %s: %w", outputFile, err)
+	}
+
+	return file.Close()
+}
+
+// openOutputFile opens the output file for streamed output, appending with --append
+func openOutputFile() (*os.File, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendOutput {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(outputFile, flags, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file %s: %w", outputFile, err)
+	}
+
+	// an existing file keeps its permissions when it's opened
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to restrict permissions of output file %s: %w", outputFile, err)
+	}
+
+	return file, nil
+}
+
+// printSummary writes a grep-able status line to stderr, stdout is reserved for the cookie output
+func printSummary(status string, cookieCount int) {
+	if !summary {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s browser=%s domain=%s cookies=%d errors=%d\n", status, browser, domain, cookieCount, len(cookieStoreErrors))
+}
+
+// exitCode tells scripts why the run failed: 2 for flag errors, 3 if no cookie matched,
+// 4 if the cookie given by --name doesn't exist, 5 if a store couldn't be read and 1 for everything else
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, errNoCookies):
+		return 3
+	case errors.Is(err, errCookieNotExists):
+		return 4
+	case errors.Is(err, cookielib.ErrStoreRead):
+		return 5
+	}
+	return 1
+}
+
 func main() {
 	if err := run(); err != nil {
-		log.Fatal(err)
+		logDiagnostic("error", err.Error(), "")
+		os.Exit(exitCode(err))
 	}
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"time"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+	"github.com/browserutils/kooky"
+)
+
+// selectProfileUnit returns the cookies of a single store instead of mixing cookies of several logins.
+// Stores whose cookies are all still valid are preferred, among those (or among all stores, if none
+// qualifies) the store with the most recently created cookie wins.
+func selectProfileUnit(cookies []*kooky.Cookie) []*kooky.Cookie {
+	type profileUnit struct {
+		cookies  []*kooky.Cookie
+		allValid bool
+		newest   time.Time
+	}
+
+	var order []string
+	units := make(map[string]*profileUnit)
+	for _, cookie := range cookies {
+		// cookies without a recorded store (e.g. from --from-json) form one unit
+		key := cookieSources[cookie].FilePath
+		unit, ok := units[key]
+		if !ok {
+			unit = &profileUnit{allValid: true}
+			units[key] = unit
+			order = append(order, key)
+		}
+		unit.cookies = append(unit.cookies, cookie)
+		if cookielib.IsExpired(cookie) {
+			unit.allValid = false
+		}
+		if cookie.Creation.After(unit.newest) {
+			unit.newest = cookie.Creation
+		}
+	}
+
+	var best *profileUnit
+	for _, key := range order {
+		unit := units[key]
+		switch {
+		case best == nil:
+			best = unit
+		case unit.allValid != best.allValid:
+			if unit.allValid {
+				best = unit
+			}
+		case unit.newest.After(best.newest):
+			best = unit
+		}
+	}
+
+	if best == nil {
+		return cookies
+	}
+	return best.cookies
+}
+
+// dedupeCookies keeps one cookie per name for --dedupe, so the name keyed outputs have plain keys:
+// latest-expiry keeps the cookie that expires last (session cookies never expire), longest-path the one
+// with the most specific path, which browsers send first. per-store keeps the cookies of one
+// store like --merge-strategy profile-unit. On ties, the cookie read last wins.
+func dedupeCookies(cookies []*kooky.Cookie, policy string) []*kooky.Cookie {
+	if policy == "per-store" {
+		return selectProfileUnit(cookies)
+	}
+
+	var deduped []*kooky.Cookie
+	indexByName := make(map[string]int)
+	for _, cookie := range cookies {
+		i, ok := indexByName[cookieKey(cookie)]
+		if !ok {
+			indexByName[cookieKey(cookie)] = len(deduped)
+			deduped = append(deduped, cookie)
+			continue
+		}
+		if !isPreferredDuplicate(deduped[i], cookie, policy) {
+			deduped[i] = cookie
+		}
+	}
+
+	return deduped
+}
+
+// isPreferredDuplicate reports whether kept beats candidate under the policy
+func isPreferredDuplicate(kept *kooky.Cookie, candidate *kooky.Cookie, policy string) bool {
+	switch policy {
+	case "latest-expiry":
+		switch {
+		case cookielib.IsSession(candidate):
+			return false
+		case cookielib.IsSession(kept):
+			return true
+		}
+		return kept.Expires.After(candidate.Expires)
+	case "longest-path":
+		return len(kept.Path) > len(candidate.Path)
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+)
+
+type storePermission struct {
+	Browser  string `json:"browser"`
+	Profile  string `json:"profile"`
+	Path     string `json:"path"`
+	Exists   bool   `json:"exists"`
+	Readable bool   `json:"readable"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+// diagnoseStorePermissions checks for every discovered store whether the current user can open its file
+func diagnoseStorePermissions() []storePermission {
+	cookieStores := cookielib.FindStores("all", nil)
+	results := make([]storePermission, 0, len(cookieStores))
+
+	for _, store := range cookieStores {
+		defer store.Close()
+
+		result := storePermission{
+			Browser: store.Browser(),
+			Profile: store.Profile(),
+			Path:    store.FilePath(),
+		}
+
+		if _, err := os.Stat(result.Path); err != nil {
+			result.Problem = describePermissionError(result.Path, err)
+			results = append(results, result)
+			continue
+		}
+		result.Exists = true
+
+		file, err := os.Open(result.Path)
+		if err != nil {
+			result.Problem = describePermissionError(result.Path, err)
+		} else {
+			file.Close()
+			result.Readable = true
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func describePermissionError(path string, err error) string {
+	switch {
+	case errors.Is(err, os.ErrPermission):
+		return "you don't have permission to read " + path
+	case errors.Is(err, os.ErrNotExist):
+		return path + " does not exist"
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/browserutils/kooky"
+)
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// pickDomain lets the user narrow the cookies down to one of the matched domains.
+// Without a terminal, or if only one domain matched, the cookies are returned unchanged.
+func pickDomain(cookies []*kooky.Cookie) ([]*kooky.Cookie, error) {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return cookies, nil
+	}
+
+	counts := make(map[string]int)
+	for _, cookie := range cookies {
+		counts[cookie.Domain]++
+	}
+	if len(counts) < 2 {
+		return cookies, nil
+	}
+
+	domains := make([]string, 0, len(counts))
+	for cookieDomain := range counts {
+		domains = append(domains, cookieDomain)
+	}
+	sort.Strings(domains)
+
+	fmt.Fprintf(os.Stderr, "%s matches %d domains:\n", domain, len(domains))
+	for i, cookieDomain := range domains {
+		fmt.Fprintf(os.Stderr, "  %d) %s (%d cookies)\n", i+1, cookieDomain, counts[cookieDomain])
+	}
+	fmt.Fprint(os.Stderr, "Pick a domain (empty for all): ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("failed to read the choice: %w", err)
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return cookies, nil
+	}
+
+	choice, err := strconv.Atoi(line)
+	if err != nil || choice < 1 || choice > len(domains) {
+		return nil, fmt.Errorf("invalid choice %q, expected a number between 1 and %d", line, len(domains))
+	}
+
+	picked := domains[choice-1]
+	var pickedCookies []*kooky.Cookie
+	for _, cookie := range cookies {
+		if cookie.Domain == picked {
+			pickedCookies = append(pickedCookies, cookie)
+		}
+	}
+
+	return pickedCookies, nil
+}
@@ -0,0 +1,315 @@
+// Package cookies reads cookies from the cookie stores of the installed browsers.
+// It's the library behind the cookies command, which adds the output modes on top.
+package cookies
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/browserutils/kooky"
+	_ "github.com/browserutils/kooky/browser/chrome"
+	_ "github.com/browserutils/kooky/browser/chromium"
+	_ "github.com/browserutils/kooky/browser/edge"
+	_ "github.com/browserutils/kooky/browser/firefox"
+	_ "github.com/browserutils/kooky/browser/opera"
+	_ "github.com/browserutils/kooky/browser/safari"
+)
+
+var (
+	// ErrNoCookies is returned by Fetch if no cookie matched
+	ErrNoCookies = errors.New("no cookies found")
+	// ErrStoreRead is returned for a store that can't be read, if the error isn't passed to Options.StoreError
+	ErrStoreRead = errors.New("failed to read cookie store")
+)
+
+// Cookie is a cookie together with the store it was read from
+type Cookie struct {
+	*kooky.Cookie
+	Browser  string
+	Profile  string
+	FilePath string
+}
+
+// StoreError is passed to Options.StoreError for a store that couldn't be read
+type StoreError struct {
+	Browser  string
+	Profile  string
+	FilePath string
+	Err      error
+}
+
+func newStoreError(store kooky.CookieStore, err error) *StoreError {
+	return &StoreError{Browser: store.Browser(), Profile: store.Profile(), FilePath: store.FilePath(), Err: err}
+}
+
+func (e *StoreError) Error() string {
+	return fmt.Sprintf("%s of %s profile %s: %v", e.FilePath, e.Browser, e.Profile, e.Err)
+}
+
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+// Decryption reports whether the store was opened but a value couldn't be decrypted,
+// usually because the keyring password is missing or wrong. kooky then drops the whole store.
+func (e *StoreError) Decryption() bool {
+	return strings.Contains(e.Err.Error(), "decrypting cookie")
+}
+
+// NameDomain is an exact cookie name and domain pair, see Options.NameDomains
+type NameDomain struct {
+	Name   string
+	Domain string
+}
+
+// Options select the stores to read and the cookies to return. The zero value reads
+// every unexpired cookie of all browsers.
+type Options struct {
+	// Browser is a browser name like "chrome", a comma separated list of them or "all".
+	// "electron" reads the stores of Electron apps like Slack, VS Code and Discord.
+	Browser string
+
+	// Domain matches every cookie domain containing it, unless ExactDomain, RegistrableDomain,
+	// SuffixDomain or RegexDomain is set
+	Domain string
+	// ExactDomain only matches cookies set on exactly Domain, ignoring the leading dot of domain cookies
+	ExactDomain bool
+	// RegistrableDomain matches cookies on the registrable domain (eTLD+1) of Domain and its subdomains
+	RegistrableDomain bool
+	// SuffixDomain matches cookies on Domain and its subdomains, but not on lookalikes like badexample.com.
+	// Domain can't be a public suffix like co.uk.
+	SuffixDomain bool
+	// RegexDomain matches the cookie domain without its leading dot against Domain as regular expression
+	RegexDomain bool
+	// DomainGlob matches the cookie domain without its leading dot against a path.Match pattern
+	DomainGlob string
+	// NameDomains only keeps cookies matching one of the pairs
+	NameDomains []NameDomain
+	// Names only keeps cookies with one of the exact names, so the stores don't return
+	// cookies the caller drops anyway
+	Names []string
+	// NameGlobs keeps cookies whose name matches one of the path.Match patterns,
+	// NameRegex those whose name matches the expression. Both match the normalized names.
+	NameGlobs []string
+	NameRegex *regexp.Regexp
+	// Path matches cookies whose path starts with it
+	Path       string
+	SecureOnly bool
+	HttpOnly   bool
+	// Container only keeps Firefox cookies of the container with the name (case insensitive) or id
+	Container string
+
+	// IncludeExpired also returns cookies whose expiry has passed
+	IncludeExpired bool
+	// ExpiresWithin only keeps cookies that expire within the duration from now
+	ExpiresWithin time.Duration
+	// ExpiresAfter only keeps cookies that expire after the time, if it isn't zero
+	ExpiresAfter   time.Time
+	ExcludeSession bool
+	SessionOnly    bool
+	NonEmpty       bool
+	// ValuePrefix matches cookies whose value starts with it
+	ValuePrefix string
+	// ExcludeValue drops cookies whose value matches, after all other filters
+	ExcludeValue *regexp.Regexp
+	// ThisSession only keeps cookies created since the running browser was launched (Linux only)
+	ThisSession bool
+
+	// NormalizeNames trims whitespace from names before comparing them to NameDomains,
+	// LowercaseNames also lowercases them
+	NormalizeNames bool
+	LowercaseNames bool
+
+	// Profiles only reads the stores of the given profiles, matched against the profile name
+	// (e.g. "Person 1") or the name of the profile directory (e.g. "Profile 1")
+	Profiles []string
+	// ExcludeProfiles and ExcludeProfileGlobs skip stores by their profile name, before they are read
+	ExcludeProfiles     []string
+	ExcludeProfileGlobs []string
+	// PreferNewestStore only reads the most recently modified of the selected stores
+	PreferNewestStore bool
+
+	// StoreFile reads only the given store file instead of discovering the stores of Browser,
+	// with the reader given by StoreType or detected from the file name
+	StoreFile string
+	StoreType string
+
+	// ChromiumPassword decrypts the values of Chromium based stores instead of the password of the
+	// keyring or keychain ("Chrome Safe Storage"), e.g. on machines without a keyring daemon
+	ChromiumPassword []byte
+	// Keyring selects where the password comes from on Linux: "gnome" (Secret Service) or "kwallet"
+	// query that keyring for the entry of each browser, "basic" and "none" use the fixed key of
+	// Chromium without keyring. Empty leaves the lookup to kooky.
+	Keyring string
+
+	// Limit stops reading further stores once Limit cookies matched and returns only the first
+	// Limit cookies in the order of the stores. 0 reads every store.
+	Limit int
+
+	// PerStoreTimeout gives up on a single store after the duration. kooky can't be cancelled, the
+	// read keeps running in the background and a Reader skips the store until it's finished.
+	PerStoreTimeout time.Duration
+	// FailFast returns the first store error instead of passing it to StoreError
+	FailFast bool
+
+	// StoreError is called for every store that couldn't be read, these errors are usually safe to ignore.
+	// Errors of stores that were found are a *StoreError.
+	StoreError func(err error)
+	// Warn is called for filters that can't be applied as asked, e.g. ThisSession
+	Warn func(message string)
+	// RawCookies is called with the unfiltered cookies of every store. It can be called concurrently.
+	RawCookies func(store kooky.CookieStore, cookies []*kooky.Cookie)
+
+	// reading tracks the running reads of the stores of a Reader
+	reading *storeReads
+}
+
+func (o Options) storeError(err error) {
+	if o.StoreError != nil {
+		o.StoreError(err)
+	}
+}
+
+func (o Options) warn(message string) {
+	if o.Warn != nil {
+		o.Warn(message)
+	}
+}
+
+// Fetch reads the cookies matching opts. Stores that can't be read are reported to
+// opts.StoreError and skipped, ErrNoCookies is returned if no cookie matched.
+func Fetch(opts Options) ([]Cookie, error) {
+	filters, err := opts.Filters()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.StoreFile != "" {
+		return fetchStoreFile(opts, filters)
+	}
+
+	cookieStores := FindStores(opts.Browser, opts.StoreError)
+	selected := opts.SelectStores(cookieStores)
+	closeUnselectedStores(cookieStores, selected)
+
+	return fetchStores(selected, filters, opts)
+}
+
+// Domains lists the cookie domains of the stores of opts.Browser, the domains of the most recently
+// created cookies first. The cookies are only seen by a filter that rejects all of them, kooky
+// filters before it decrypts, so no value is decrypted and the keyring isn't asked for the key.
+func Domains(opts Options) []string {
+	cookieStores := FindStores(opts.Browser, opts.StoreError)
+	selected := opts.SelectStores(cookieStores)
+	closeUnselectedStores(cookieStores, selected)
+	return readDomains(selected, opts)
+}
+
+func readDomains(cookieStores []kooky.CookieStore, opts Options) []string {
+	var mu sync.Mutex
+	created := make(map[string]time.Time)
+	collect := kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		cookieDomain := strings.TrimPrefix(cookie.Domain, ".")
+		mu.Lock()
+		defer mu.Unlock()
+		if latest, ok := created[cookieDomain]; !ok || cookie.Creation.After(latest) {
+			created[cookieDomain] = cookie.Creation
+		}
+		return false
+	})
+
+	// a dump or a limit would need the cookies themselves
+	opts.RawCookies, opts.Limit = nil, 0
+	readCookieStores(cookieStores, []kooky.Filter{collect}, opts)
+
+	// timed out reads still call the filter
+	mu.Lock()
+	defer mu.Unlock()
+	domains := make([]string, 0, len(created))
+	for cookieDomain := range created {
+		domains = append(domains, cookieDomain)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if !created[domains[i]].Equal(created[domains[j]]) {
+			return created[domains[i]].After(created[domains[j]])
+		}
+		return domains[i] < domains[j]
+	})
+	return domains
+}
+
+// fetchStores reads the selected stores and closes them afterwards
+func fetchStores(selected []kooky.CookieStore, filters []kooky.Filter, opts Options) ([]Cookie, error) {
+	// tells an undetected browser apart from one without matching cookies
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w, no cookie store of browser %s was found", ErrNoCookies, opts.Browser)
+	}
+
+	opts.applyKeyring(selected)
+
+	var cookies []Cookie
+	// results are in the order of the stores, so the cookie read last still wins duplicates
+	for i, result := range readCookieStores(selected, filters, opts) {
+		store := selected[i]
+		if result.err != nil {
+			if opts.FailFast {
+				return nil, fmt.Errorf("%w %s of %s profile %s: %w", ErrStoreRead, store.FilePath(), store.Browser(), store.Profile(), result.err)
+			}
+			opts.storeError(newStoreError(store, result.err))
+		}
+
+		cookies = append(cookies, withSource(store, result.cookies)...)
+	}
+
+	if opts.Limit > 0 && len(cookies) > opts.Limit {
+		cookies = cookies[:opts.Limit]
+	}
+
+	if cookies == nil {
+		return nil, fmt.Errorf("%w for browser %s and domain %s", ErrNoCookies, opts.Browser, opts.Domain)
+	}
+
+	return cookies, nil
+}
+
+// fetchStoreFile reads opts.StoreFile, errors are not ignored as it's the only store
+func fetchStoreFile(opts Options, filters []kooky.Filter) ([]Cookie, error) {
+	store, err := OpenStoreFile(opts.StoreFile, opts.StoreType)
+	if err != nil {
+		return nil, err
+	}
+	opts.applyKeyring([]kooky.CookieStore{store})
+
+	cookies, err := readAndCloseStore(store, filters, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s as %s store, try another store type: %w", ErrStoreRead, opts.StoreFile, store.Browser(), err)
+	}
+
+	if len(cookies) == 0 {
+		return nil, fmt.Errorf("%w in %s for domain %s", ErrNoCookies, opts.StoreFile, opts.Domain)
+	}
+
+	if opts.Limit > 0 && len(cookies) > opts.Limit {
+		cookies = cookies[:opts.Limit]
+	}
+
+	return withSource(store, cookies), nil
+}
+
+func withSource(store kooky.CookieStore, cookies []*kooky.Cookie) []Cookie {
+	sourced := make([]Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		sourced = append(sourced, Cookie{
+			Cookie:   cookie,
+			Browser:  store.Browser(),
+			Profile:  store.Profile(),
+			FilePath: store.FilePath(),
+		})
+	}
+	return sourced
+}
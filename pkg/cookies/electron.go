@@ -0,0 +1,70 @@
+package cookies
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/browserutils/kooky"
+	"github.com/browserutils/kooky/browser/chrome"
+)
+
+// electronApps are the user config directory names of well-known Electron apps
+var electronApps = map[string]string{
+	"slack":   "Slack",
+	"vscode":  "Code",
+	"discord": "discord",
+}
+
+// relabeledCookieStore reports a store read with the Chrome reader under another browser and profile,
+// e.g. Electron app stores as browser "electron" with the app as profile
+type relabeledCookieStore struct {
+	kooky.CookieStore
+	browser   string
+	profile   string
+	isDefault bool
+}
+
+func (s *relabeledCookieStore) Browser() string        { return s.browser }
+func (s *relabeledCookieStore) Profile() string        { return s.profile }
+func (s *relabeledCookieStore) IsDefaultProfile() bool { return s.isDefault }
+
+// findElectronCookieStores looks for the Chromium cookie databases of well-known Electron apps
+func findElectronCookieStores(storeError func(err error)) []kooky.CookieStore {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+
+	apps := make([]string, 0, len(electronApps))
+	for app := range electronApps {
+		apps = append(apps, app)
+	}
+	// a stable order, as the store read last wins duplicate cookies
+	sort.Strings(apps)
+
+	var cookieStores []kooky.CookieStore
+	for _, app := range apps {
+		dirName := electronApps[app]
+		// newer Chromium versions moved the database into the Network subdirectory
+		for _, candidate := range []string{
+			filepath.Join(configDir, dirName, "Network", "Cookies"),
+			filepath.Join(configDir, dirName, "Cookies"),
+		} {
+			if _, err := os.Stat(candidate); err != nil {
+				continue
+			}
+			store, err := chrome.CookieStore(candidate)
+			if err != nil {
+				if storeError != nil {
+					storeError(err)
+				}
+				continue
+			}
+			cookieStores = append(cookieStores, &relabeledCookieStore{CookieStore: store, browser: "electron", profile: app})
+			break
+		}
+	}
+
+	return cookieStores
+}
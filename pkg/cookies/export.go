@@ -0,0 +1,231 @@
+package cookies
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a file format understood by Export
+type Format string
+
+const (
+	// FormatNetscape is the cookies.txt layout understood by curl, wget and yt-dlp
+	FormatNetscape Format = "netscape"
+	// FormatCSV has a header row and the columns Name, Value, Domain, Path, Expires, Secure and HttpOnly
+	FormatCSV Format = "csv"
+	// FormatPlaywright is the storageState JSON of Playwright, which Puppeteer can read as well
+	FormatPlaywright Format = "playwright"
+	// FormatJSONLines writes one JSON object per line and cookie, e.g. for log pipelines
+	FormatJSONLines Format = "jsonl"
+)
+
+// Export writes the cookies in format, ordered by name, domain and path so the output is stable between runs
+func Export(w io.Writer, cookies []Cookie, format Format) error {
+	sorted := make([]Cookie, len(cookies))
+	copy(sorted, cookies)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		if sorted[i].Domain != sorted[j].Domain {
+			return sorted[i].Domain < sorted[j].Domain
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	switch format {
+	case FormatNetscape:
+		return exportNetscape(w, sorted)
+	case FormatCSV:
+		return exportCsv(w, sorted)
+	case FormatPlaywright:
+		return exportPlaywright(w, sorted)
+	case FormatJSONLines:
+		return exportJsonLines(w, sorted)
+	}
+	return fmt.Errorf("unsupported export format %s", format)
+}
+
+// exportNetscape writes the Netscape cookies.txt layout, session cookies get an expiry of 0
+func exportNetscape(w io.Writer, cookies []Cookie) error {
+	if _, err := io.WriteString(w, "# Netscape HTTP Cookie File\n"); err != nil {
+		return err
+	}
+
+	for _, cookie := range cookies {
+		var expires int64
+		if !IsSession(cookie.Cookie) {
+			expires = cookie.Expires.Unix()
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			cookie.Domain,
+			netscapeBool(strings.HasPrefix(cookie.Domain, ".")),
+			cookie.Path,
+			netscapeBool(cookie.Secure),
+			expires,
+			cookie.Name,
+			cookie.Value,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// exportCsv writes a header row and one row per cookie, Expires is in UTC and empty for session cookies
+func exportCsv(w io.Writer, cookies []Cookie) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Name", "Value", "Domain", "Path", "Expires", "Secure", "HttpOnly"}); err != nil {
+		return err
+	}
+
+	for _, cookie := range cookies {
+		var expires string
+		if !IsSession(cookie.Cookie) {
+			expires = cookie.Expires.UTC().Format(time.RFC3339)
+		}
+		record := []string{
+			cookie.Name,
+			cookie.Value,
+			cookie.Domain,
+			cookie.Path,
+			expires,
+			strconv.FormatBool(cookie.Secure),
+			strconv.FormatBool(cookie.HttpOnly),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// jsonLinesCookie is a line of FormatJSONLines, Expires is nil for session cookies
+type jsonLinesCookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Domain   string     `json:"domain"`
+	Path     string     `json:"path"`
+	Expires  *time.Time `json:"expires"`
+	Secure   bool       `json:"secure"`
+	HttpOnly bool       `json:"httpOnly"`
+	Browser  string     `json:"browser"`
+	Profile  string     `json:"profile"`
+	Store    string     `json:"store"`
+	// Container is only set for cookies of Firefox containers
+	Container string `json:"container,omitempty"`
+}
+
+func exportJsonLines(w io.Writer, cookies []Cookie) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, cookie := range cookies {
+		line := jsonLinesCookie{
+			Name:      cookie.Name,
+			Value:     cookie.Value,
+			Domain:    cookie.Domain,
+			Path:      cookie.Path,
+			Secure:    cookie.Secure,
+			HttpOnly:  cookie.HttpOnly,
+			Browser:   cookie.Browser,
+			Profile:   cookie.Profile,
+			Store:     cookie.FilePath,
+			Container: cookie.Container,
+		}
+		if !IsSession(cookie.Cookie) {
+			expires := cookie.Expires.UTC()
+			line.Expires = &expires
+		}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type playwrightCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"`
+	HttpOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	SameSite string  `json:"sameSite"`
+}
+
+type playwrightStorageState struct {
+	Cookies []playwrightCookie `json:"cookies"`
+	// the local storage of the origins isn't stored with the cookies, so it's always empty
+	Origins []struct{} `json:"origins"`
+}
+
+// exportPlaywright writes a storageState that can be passed to browser.newContext({storageState}).
+// Session cookies get the expiry -1 Playwright uses for them.
+func exportPlaywright(w io.Writer, cookies []Cookie) error {
+	state := playwrightStorageState{
+		Cookies: make([]playwrightCookie, 0, len(cookies)),
+		Origins: []struct{}{},
+	}
+
+	for _, cookie := range cookies {
+		expires := float64(-1)
+		if !IsSession(cookie.Cookie) {
+			expires = float64(cookie.Expires.Unix())
+		}
+		state.Cookies = append(state.Cookies, playwrightCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Expires:  expires,
+			HttpOnly: cookie.HttpOnly,
+			Secure:   cookie.Secure,
+			SameSite: playwrightSameSite(cookie.SameSite),
+		})
+	}
+
+	return json.NewEncoder(w).Encode(state)
+}
+
+// playwrightSameSite maps the SameSite mode to the values Playwright accepts,
+// cookies without an explicit mode are treated as Lax like browsers do
+func playwrightSameSite(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return "Lax"
+}
+
+// CookieHeader joins the cookies into the value of a Cookie request header, using their raw names.
+// The pairs are separated by "; " as RFC 6265 5.4 requires.
+func CookieHeader(cookies []Cookie) string {
+	var cookieParts []string
+
+	for _, cookie := range cookies {
+		cookieParts = append(cookieParts, fmt.Sprintf("%s=%s", cookie.Name, cookie.Value))
+	}
+
+	return strings.Join(cookieParts, "; ")
+}
@@ -0,0 +1,334 @@
+package cookies
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/browserutils/kooky"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Filters translates the options into kooky filters, e.g. for filtering cookies that weren't read by Fetch
+func (o Options) Filters() ([]kooky.Filter, error) {
+	filters := o.stateFilters()
+
+	if o.RegistrableDomain {
+		domainFilter, err := registrableDomainFilter(o.Domain)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, domainFilter)
+	} else if o.ExactDomain {
+		filters = append(filters, exactDomainFilter(o.Domain))
+	} else if o.SuffixDomain {
+		domainFilter, err := suffixDomainFilter(o.Domain)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, domainFilter)
+	} else if o.RegexDomain {
+		expr, err := regexp.Compile(o.Domain)
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain regular expression %q: %w", o.Domain, err)
+		}
+		filters = append(filters, kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+			return expr.MatchString(strings.TrimPrefix(cookie.Domain, "."))
+		}))
+	} else if o.Domain != "" {
+		filters = append(filters, kooky.DomainContains(o.Domain))
+	}
+
+	if o.DomainGlob != "" {
+		if _, err := path.Match(o.DomainGlob, ""); err != nil {
+			return nil, fmt.Errorf("invalid domain glob %q: %w", o.DomainGlob, err)
+		}
+		filters = append(filters, domainGlobFilter(o.DomainGlob))
+	}
+
+	if len(o.NameDomains) > 0 {
+		filters = append(filters, o.nameDomainFilter())
+	}
+
+	if len(o.Names) > 0 {
+		filters = append(filters, o.nameFilter())
+	}
+
+	if len(o.NameGlobs) > 0 {
+		for _, pattern := range o.NameGlobs {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid name glob %q: %w", pattern, err)
+			}
+		}
+		filters = append(filters, o.nameGlobFilter())
+	}
+
+	if o.NameRegex != nil {
+		filters = append(filters, kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+			return o.NameRegex.MatchString(o.normalizeName(cookie.Name))
+		}))
+	}
+
+	if o.Path != "" {
+		filters = append(filters, kooky.PathHasPrefix(o.Path))
+	}
+
+	if o.SecureOnly {
+		filters = append(filters, kooky.Secure)
+	}
+
+	if o.HttpOnly {
+		filters = append(filters, kooky.HTTPOnly)
+	}
+
+	if o.Container != "" {
+		filters = append(filters, containerFilter(o.Container))
+	}
+
+	if o.ValuePrefix != "" {
+		filters = append(filters, kooky.ValueHasPrefix(o.ValuePrefix))
+	}
+
+	// exclusions come after all inclusion filters
+	if o.ExcludeValue != nil {
+		// a value filter, so kooky applies it after decrypting the value
+		filters = append(filters, kooky.ValueFilterFunc(func(cookie *kooky.Cookie) bool {
+			return !o.ExcludeValue.MatchString(cookie.Value)
+		}))
+	}
+
+	if o.ThisSession {
+		if sessionFilter, err := o.thisSessionFilter(); err != nil {
+			o.warn(fmt.Sprintf("ignoring the browser session filter: %s", err))
+		} else {
+			filters = append(filters, sessionFilter)
+		}
+	}
+
+	return filters, nil
+}
+
+// registrableDomainFilter matches cookies set on the eTLD+1 of domain or any of its subdomains,
+// e.g. app.example.co.uk matches cookies for example.co.uk and www.example.co.uk but not co.uk
+func registrableDomainFilter(domain string) (kooky.Filter, error) {
+	etldPlusOne, err := publicsuffix.EffectiveTLDPlusOne(strings.TrimPrefix(domain, "."))
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine registrable domain of %s: %w", domain, err)
+	}
+
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		cookieDomain := strings.TrimPrefix(cookie.Domain, ".")
+		return cookieDomain == etldPlusOne || strings.HasSuffix(cookieDomain, "."+etldPlusOne)
+	}), nil
+}
+
+// suffixDomainFilter matches cookies set on domain or any of its subdomains, e.g. example.com matches
+// app.example.com but not badexample.com. Public suffixes like co.uk or github.io are rejected, they
+// would match the cookies of unrelated sites. Single labels that aren't a TLD, like localhost, are fine.
+func suffixDomainFilter(domain string) (kooky.Filter, error) {
+	domain = strings.TrimPrefix(domain, ".")
+	if suffix, icann := publicsuffix.PublicSuffix(domain); suffix == domain && (icann || strings.Contains(domain, ".")) {
+		return nil, fmt.Errorf("%s is a public suffix, it would match the cookies of unrelated sites", domain)
+	}
+
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		cookieDomain := strings.TrimPrefix(cookie.Domain, ".")
+		return cookieDomain == domain || strings.HasSuffix(cookieDomain, "."+domain)
+	}), nil
+}
+
+// exactDomainFilter matches cookies set on exactly domain, ignoring the leading dot of domain cookies
+func exactDomainFilter(domain string) kooky.Filter {
+	domain = strings.TrimPrefix(domain, ".")
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		return strings.TrimPrefix(cookie.Domain, ".") == domain
+	})
+}
+
+// thisSessionFilter keeps cookies created after the browser process was started.
+// Cookies without a creation time can't be judged and are kept.
+func (o Options) thisSessionFilter() (kooky.Filter, error) {
+	startTime, err := browserStartTime(o.Browser)
+	if err != nil {
+		return nil, err
+	}
+
+	// stores are read concurrently, so the filter can run on several goroutines
+	var warning sync.Once
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		if cookie.Creation.IsZero() {
+			warning.Do(func() {
+				o.warn("some cookies have no creation time, keeping them for the browser session filter")
+			})
+			return true
+		}
+		return cookie.Creation.After(startTime)
+	}), nil
+}
+
+// nameDomainFilter keeps cookies matching one of the exact name and domain pairs,
+// the leading dot of domain cookies is ignored
+func (o Options) nameDomainFilter() kooky.Filter {
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		cookieDomain := strings.TrimPrefix(cookie.Domain, ".")
+		for _, pair := range o.NameDomains {
+			if o.normalizeName(cookie.Name) == o.normalizeName(pair.Name) && cookieDomain == strings.TrimPrefix(pair.Domain, ".") {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// nameFilter keeps cookies whose name is one of o.Names
+func (o Options) nameFilter() kooky.Filter {
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		name := o.normalizeName(cookie.Name)
+		for _, wanted := range o.Names {
+			if name == o.normalizeName(wanted) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// nameGlobFilter keeps cookies whose name matches any of o.NameGlobs
+func (o Options) nameGlobFilter() kooky.Filter {
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		name := o.normalizeName(cookie.Name)
+		for _, pattern := range o.NameGlobs {
+			// the patterns are validated in Filters
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (o Options) normalizeName(name string) string {
+	if !o.NormalizeNames {
+		return name
+	}
+	return NormalizeName(name, o.LowercaseNames)
+}
+
+// NormalizeName trims the whitespace that some stores report around cookie names
+// and optionally lowercases them
+func NormalizeName(name string, lowercase bool) string {
+	name = strings.TrimSpace(name)
+	if lowercase {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// domainGlobFilter matches the cookie domain without its leading dot against a path.Match pattern
+func domainGlobFilter(pattern string) kooky.Filter {
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		// the pattern is validated in Filters
+		matched, _ := path.Match(pattern, strings.TrimPrefix(cookie.Domain, "."))
+		return matched
+	})
+}
+
+// defaultContainers are the names of the containers Firefox creates, which it stores without a name
+var defaultContainers = map[string]string{
+	"1": "personal",
+	"2": "work",
+	"3": "banking",
+	"4": "shopping",
+}
+
+// containerFilter matches the Firefox container of the cookie, which kooky reports as "id|name"
+// or only "id" for the default containers
+func containerFilter(container string) kooky.Filter {
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		if cookie.Container == "" {
+			return false
+		}
+		id, name, _ := strings.Cut(cookie.Container, "|")
+		if name == "" {
+			name = defaultContainers[id]
+		}
+		return container == id || strings.EqualFold(container, name)
+	})
+}
+
+// unexpiredFilter drops cookies whose expiry has passed. Unlike kooky.Valid it keeps
+// session cookies, they are valid until the browser is closed.
+var unexpiredFilter = kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+	return !IsExpired(cookie) && cookie.Cookie.Valid() == nil
+})
+
+// persistentFilter drops session cookies, which browsers store with a zero expiry
+var persistentFilter = kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+	return !IsSession(cookie)
+})
+
+var sessionFilter = kooky.FilterFunc(IsSession)
+
+// nonEmptyFilter is a value filter, so kooky applies it after decrypting the value
+var nonEmptyFilter = kooky.ValueFilterFunc(func(cookie *kooky.Cookie) bool {
+	return cookie.Value != ""
+})
+
+// IsSession reports whether cookie is a session cookie, which browsers store with a zero expiry
+func IsSession(cookie *kooky.Cookie) bool {
+	return cookie.Expires.IsZero()
+}
+
+// IsExpired reports whether the expiry of cookie has passed, session cookies never expire
+func IsExpired(cookie *kooky.Cookie) bool {
+	return !IsSession(cookie) && !cookie.Expires.After(time.Now())
+}
+
+// expiresWithinFilter keeps cookies expiring between now and now+window,
+// session cookies have no expiry and never match
+func expiresWithinFilter(window time.Duration) kooky.Filter {
+	deadline := time.Now().Add(window)
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		return !IsSession(cookie) && !IsExpired(cookie) && !cookie.Expires.After(deadline)
+	})
+}
+
+// expiresAfterFilter keeps cookies expiring after t, session cookies have no expiry and never match
+func expiresAfterFilter(t time.Time) kooky.Filter {
+	return kooky.FilterFunc(func(cookie *kooky.Cookie) bool {
+		return !IsSession(cookie) && cookie.Expires.After(t)
+	})
+}
+
+// stateFilters returns the validity (IncludeExpired, ExpiresWithin, ExpiresAfter), persistence (ExcludeSession, SessionOnly)
+// and presence (NonEmpty) filters. Each of them only looks at one property of a cookie,
+// so the options can be combined freely.
+func (o Options) stateFilters() []kooky.Filter {
+	var filters []kooky.Filter
+	if !o.IncludeExpired {
+		filters = append(filters, unexpiredFilter)
+	}
+
+	if o.ExpiresWithin > 0 {
+		filters = append(filters, expiresWithinFilter(o.ExpiresWithin))
+	}
+
+	if !o.ExpiresAfter.IsZero() {
+		filters = append(filters, expiresAfterFilter(o.ExpiresAfter))
+	}
+
+	if o.ExcludeSession {
+		filters = append(filters, persistentFilter)
+	} else if o.SessionOnly {
+		filters = append(filters, sessionFilter)
+	}
+
+	if o.NonEmpty {
+		filters = append(filters, nonEmptyFilter)
+	}
+
+	return filters
+}
@@ -0,0 +1,62 @@
+package cookies
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/browserutils/kooky"
+)
+
+func testCookie(name string, value string, expires time.Time) *kooky.Cookie {
+	return &kooky.Cookie{Cookie: http.Cookie{Name: name, Value: value, Domain: ".example.com", Path: "/", Expires: expires}}
+}
+
+func TestStateFilters(t *testing.T) {
+	now := time.Now()
+	cookies := []*kooky.Cookie{
+		testCookie("session", "s", time.Time{}),
+		testCookie("empty", "", time.Time{}),
+		testCookie("persistent", "p", now.Add(30*24*time.Hour)),
+		testCookie("soon", "o", now.Add(time.Hour)),
+		testCookie("expired", "e", now.Add(-time.Hour)),
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"default keeps session cookies", Options{}, []string{"session", "empty", "persistent", "soon"}},
+		{"expired", Options{IncludeExpired: true}, []string{"session", "empty", "persistent", "soon", "expired"}},
+		{"exclude session", Options{ExcludeSession: true}, []string{"persistent", "soon"}},
+		{"exclude session and expired", Options{ExcludeSession: true, IncludeExpired: true}, []string{"persistent", "soon", "expired"}},
+		{"session only", Options{SessionOnly: true}, []string{"session", "empty"}},
+		{"session only and expired", Options{SessionOnly: true, IncludeExpired: true}, []string{"session", "empty"}},
+		{"session only and non-empty", Options{SessionOnly: true, NonEmpty: true}, []string{"session"}},
+		{"non-empty", Options{NonEmpty: true}, []string{"session", "persistent", "soon"}},
+		{"expires within", Options{ExpiresWithin: 24 * time.Hour}, []string{"soon"}},
+		{"expires within and expired", Options{ExpiresWithin: 24 * time.Hour, IncludeExpired: true}, []string{"soon"}},
+		{"expires within and session only", Options{ExpiresWithin: 24 * time.Hour, SessionOnly: true}, nil},
+		{"expires after", Options{ExpiresAfter: now.Add(2 * time.Hour)}, []string{"persistent"}},
+		{"expires after and exclude session", Options{ExpiresAfter: now.Add(-2 * time.Hour), ExcludeSession: true, IncludeExpired: true}, []string{"persistent", "soon", "expired"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filters, err := test.opts.Filters()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, cookie := range kooky.FilterCookies(cookies, filters...) {
+				got = append(got, cookie.Name)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package cookies
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/browserutils/kooky"
+	"github.com/browserutils/kooky/browser/chrome"
+)
+
+// chromiumForks are Chromium based browsers kooky has no finder for, with their user data
+// directory relative to the application data directory of the platform
+var chromiumForks = map[string]map[string]string{
+	"brave": {
+		"linux":   filepath.Join("BraveSoftware", "Brave-Browser"),
+		"darwin":  filepath.Join("BraveSoftware", "Brave-Browser"),
+		"windows": filepath.Join("BraveSoftware", "Brave-Browser", "User Data"),
+	},
+	"vivaldi": {
+		"linux":   "vivaldi",
+		"darwin":  "Vivaldi",
+		"windows": filepath.Join("Vivaldi", "User Data"),
+	},
+}
+
+// forkAppDataDir is the directory the user data directories of chromiumForks are relative to.
+// Chromium keeps them in %LOCALAPPDATA% on Windows instead of the roaming config directory.
+func forkAppDataDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+			return localAppData, nil
+		}
+	}
+	return os.UserConfigDir()
+}
+
+// findChromiumForkCookieStores finds the stores of every profile of the chromiumForks,
+// they are read with the Chrome reader and reported with the directory as profile
+func findChromiumForkCookieStores(storeError func(err error)) []kooky.CookieStore {
+	appDataDir, err := forkAppDataDir()
+	if err != nil {
+		return nil
+	}
+
+	forks := make([]string, 0, len(chromiumForks))
+	for fork := range chromiumForks {
+		forks = append(forks, fork)
+	}
+	// a stable order, as the store read last wins duplicate cookies
+	sort.Strings(forks)
+
+	var cookieStores []kooky.CookieStore
+	for _, fork := range forks {
+		userDataDir, ok := chromiumForks[fork][runtime.GOOS]
+		if !ok {
+			continue
+		}
+		profileDirs, err := filepath.Glob(filepath.Join(appDataDir, userDataDir, "*"))
+		if err != nil {
+			continue
+		}
+
+		for _, profileDir := range profileDirs {
+			// newer Chromium versions moved the database into the Network subdirectory
+			for _, candidate := range []string{
+				filepath.Join(profileDir, "Network", "Cookies"),
+				filepath.Join(profileDir, "Cookies"),
+			} {
+				if _, err := os.Stat(candidate); err != nil {
+					continue
+				}
+				store, err := chrome.CookieStore(candidate)
+				if err != nil {
+					if storeError != nil {
+						storeError(err)
+					}
+					continue
+				}
+				profile := filepath.Base(profileDir)
+				cookieStores = append(cookieStores, &relabeledCookieStore{
+					CookieStore: store,
+					browser:     fork,
+					profile:     profile,
+					isDefault:   profile == "Default",
+				})
+				break
+			}
+		}
+	}
+
+	return cookieStores
+}
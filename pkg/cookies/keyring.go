@@ -0,0 +1,147 @@
+package cookies
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+
+	"github.com/browserutils/kooky"
+)
+
+// Keyrings are the values of Options.Keyring
+const Keyrings = "gnome, kwallet, basic or none"
+
+// ErrKeyring is the error of a StoreError if the password of Options.Keyring couldn't be read
+var ErrKeyring = errors.New("failed to read the keyring password")
+
+// basicPassword is the key Chromium encrypts the values with if it has no keyring (--password-store=basic)
+var basicPassword = []byte("peanuts")
+
+// keyringEntry is where Chromium keeps the password of a browser in the Linux keyrings
+type keyringEntry struct {
+	// application is the attribute of the Secret Service item
+	application string
+	// product names the KWallet folder "<product> Keys" and entry "<product> Safe Storage"
+	product string
+}
+
+// keyringEntries are the entries of each browser, the first one found is used
+var keyringEntries = map[string][]keyringEntry{
+	"chrome":   {{application: "chrome", product: "Chrome"}},
+	"chromium": {{application: "chromium", product: "Chromium"}},
+	// kooky reads Opera with Chromium's key
+	"opera": {{application: "chromium", product: "Chromium"}},
+	"brave": {{application: "brave", product: "Brave"}},
+	// Edge and Vivaldi have their own entries in newer versions, older ones share them with Chromium and Chrome
+	"edge":    {{application: "microsoft-edge", product: "Microsoft Edge"}, {application: "chromium", product: "Chromium"}},
+	"vivaldi": {{application: "vivaldi", product: "Vivaldi"}, {application: "chrome", product: "Chrome"}},
+}
+
+// keyringPasswordSetter is implemented by kooky's Chromium stores
+type keyringPasswordSetter interface {
+	SetKeyringPassword(password []byte) []byte
+}
+
+// chromiumStore returns the Chromium store behind store, nil for the stores of other browsers.
+// kooky wraps its stores in a jar that embeds the store as exported field of an internal type.
+func chromiumStore(store kooky.CookieStore) keyringPasswordSetter {
+	if relabeled, ok := store.(*relabeledCookieStore); ok {
+		store = relabeled.CookieStore
+	}
+	if setter, ok := store.(keyringPasswordSetter); ok {
+		return setter
+	}
+
+	jar := reflect.ValueOf(store)
+	if jar.Kind() != reflect.Pointer || jar.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	embedded := jar.Elem().FieldByName("CookieStore")
+	if !embedded.IsValid() || !embedded.CanInterface() {
+		return nil
+	}
+	setter, _ := embedded.Interface().(keyringPasswordSetter)
+	return setter
+}
+
+// keyringPassword returns the password the Chromium stores of browser are decrypted with,
+// nil leaves the lookup to kooky
+func (o Options) keyringPassword(browser string) ([]byte, error) {
+	if len(o.ChromiumPassword) > 0 {
+		return o.ChromiumPassword, nil
+	}
+
+	switch o.Keyring {
+	case "":
+		return nil, nil
+	case "basic", "none":
+		return basicPassword, nil
+	case "gnome", "kwallet":
+	default:
+		return nil, fmt.Errorf("unknown keyring %q, use one of %s", o.Keyring, Keyrings)
+	}
+
+	entries, ok := keyringEntries[browser]
+	if !ok {
+		return nil, fmt.Errorf("%w: the %s keyring entry of browser %s is unknown, pass the password instead", ErrKeyring, o.Keyring, browser)
+	}
+	var lookupErr error
+	for _, entry := range entries {
+		var command *exec.Cmd
+		if o.Keyring == "gnome" {
+			command = exec.Command("secret-tool", "lookup", "application", entry.application)
+		} else {
+			command = exec.Command("kwallet-query", "--read-password", entry.product+" Safe Storage", "--folder", entry.product+" Keys", "kdewallet")
+		}
+		output, err := command.Output()
+		if err != nil {
+			// the first error is reported, it's the entry of the browser itself
+			if lookupErr == nil {
+				lookupErr = fmt.Errorf("%w from %s with %s: %w", ErrKeyring, o.Keyring, command.Path, err)
+			}
+			continue
+		}
+		if password := bytes.TrimRight(output, "\r\n"); len(password) > 0 {
+			return password, nil
+		}
+	}
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	return nil, fmt.Errorf("%w: the %s keyring has no password for browser %s", ErrKeyring, o.Keyring, browser)
+}
+
+// applyKeyring gives the Chromium stores the password of Options.ChromiumPassword or Options.Keyring.
+// A store whose password can't be looked up is reported to Options.StoreError and keeps kooky's lookup.
+func (o Options) applyKeyring(cookieStores []kooky.CookieStore) {
+	if len(o.ChromiumPassword) == 0 && o.Keyring == "" {
+		return
+	}
+
+	type lookup struct {
+		password []byte
+		err      error
+	}
+	lookups := make(map[string]lookup)
+	for _, store := range cookieStores {
+		setter := chromiumStore(store)
+		if setter == nil {
+			continue
+		}
+
+		looked, ok := lookups[store.Browser()]
+		if !ok {
+			looked.password, looked.err = o.keyringPassword(store.Browser())
+			lookups[store.Browser()] = looked
+		}
+		if looked.err != nil {
+			o.storeError(newStoreError(store, looked.err))
+			continue
+		}
+		if looked.password != nil {
+			setter.SetKeyringPassword(looked.password)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package cookies
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/browserutils/kooky"
+)
+
+// storeReadWorkers bounds the number of stores that are read at the same time
+var storeReadWorkers = runtime.GOMAXPROCS(0)
+
+type storeResult struct {
+	cookies []*kooky.Cookie
+	err     error
+}
+
+// readCookieStores reads the stores concurrently and returns their results in the order of cookieStores.
+// Each store is closed as soon as it has been read. With opts.Limit no further store is read
+// once enough cookies were found, the results of those stores are empty.
+func readCookieStores(cookieStores []kooky.CookieStore, filters []kooky.Filter, opts Options) []storeResult {
+	results := make([]storeResult, len(cookieStores))
+	jobs := make(chan int)
+	var found atomic.Int64
+
+	var wg sync.WaitGroup
+	for range min(storeReadWorkers, len(cookieStores)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every worker writes its own indexes of results, so no locking is needed
+			for i := range jobs {
+				cookies, err := readAndCloseStore(cookieStores[i], filters, opts)
+				results[i] = storeResult{cookies, err}
+				found.Add(int64(len(cookies)))
+			}
+		}()
+	}
+
+	for i := range cookieStores {
+		if opts.Limit > 0 && found.Load() >= int64(opts.Limit) {
+			cookieStores[i].Close()
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// readAndCloseStore reads a single store, bounded by opts.PerStoreTimeout if set, and closes it
+// once the read is done. A timed out read still uses the store, so it's closed in the background.
+func readAndCloseStore(store kooky.CookieStore, filters []kooky.Filter, opts Options) ([]*kooky.Cookie, error) {
+	if opts.PerStoreTimeout <= 0 {
+		defer store.Close()
+		return readStoreCookiesUnbounded(store, filters, opts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.PerStoreTimeout)
+	defer cancel()
+
+	// kooky can't be cancelled, a timed out read is abandoned and finishes in the background
+	done := make(chan storeResult, 1)
+	opts.reading.start(store)
+	go func() {
+		cookies, err := readStoreCookiesUnbounded(store, filters, opts)
+		store.Close()
+		opts.reading.finish(store)
+		done <- storeResult{cookies, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.cookies, result.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("reading cookie store %s timed out after %s", store.FilePath(), opts.PerStoreTimeout)
+	}
+}
+
+// storeReads are the stores of a Reader that are read at the moment, including timed out
+// reads that still run in the background
+type storeReads struct {
+	mu     sync.Mutex
+	stores map[kooky.CookieStore]bool
+}
+
+// start and finish can be called on a nil storeReads, the stores of Fetch aren't reused
+func (s *storeReads) start(store kooky.CookieStore) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stores == nil {
+		s.stores = make(map[kooky.CookieStore]bool)
+	}
+	s.stores[store] = true
+}
+
+func (s *storeReads) finish(store kooky.CookieStore) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.stores, store)
+}
+
+func (s *storeReads) running(store kooky.CookieStore) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stores[store]
+}
+
+func readStoreCookiesUnbounded(store kooky.CookieStore, filters []kooky.Filter, opts Options) ([]*kooky.Cookie, error) {
+	if opts.RawCookies == nil {
+		return store.ReadCookies(filters...)
+	}
+
+	// read everything and filter afterwards, so the dump shows what kooky actually returned
+	cookies, err := store.ReadCookies()
+	opts.RawCookies(store, cookies)
+	return kooky.FilterCookies(cookies, filters...), err
+}
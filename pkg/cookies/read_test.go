@@ -0,0 +1,159 @@
+package cookies
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/browserutils/kooky"
+)
+
+// fakeStore is a cookie store whose reads take delay, like a store on disk that has to be decrypted
+type fakeStore struct {
+	kooky.CookieStore
+	path    string
+	delay   time.Duration
+	cookies []*kooky.Cookie
+	reading atomic.Bool
+	closed  atomic.Bool
+	// closedWhileReading is set if the store was closed before its read returned
+	closedWhileReading atomic.Bool
+}
+
+func newFakeStore(i int, delay time.Duration) *fakeStore {
+	return &fakeStore{
+		path:    fmt.Sprintf("/profiles/%d/Cookies", i),
+		delay:   delay,
+		cookies: []*kooky.Cookie{testCookie(fmt.Sprintf("c%d", i), "v", time.Time{})},
+	}
+}
+
+func (s *fakeStore) ReadCookies(filters ...kooky.Filter) ([]*kooky.Cookie, error) {
+	s.reading.Store(true)
+	defer s.reading.Store(false)
+	time.Sleep(s.delay)
+	return kooky.FilterCookies(s.cookies, filters...), nil
+}
+
+func (s *fakeStore) Browser() string        { return "fake" }
+func (s *fakeStore) Profile() string        { return s.path }
+func (s *fakeStore) IsDefaultProfile() bool { return false }
+func (s *fakeStore) FilePath() string       { return s.path }
+
+func (s *fakeStore) Close() error {
+	if s.reading.Load() {
+		s.closedWhileReading.Store(true)
+	}
+	s.closed.Store(true)
+	return nil
+}
+
+func fakeStores(n int, delay time.Duration) []kooky.CookieStore {
+	cookieStores := make([]kooky.CookieStore, n)
+	for i := range cookieStores {
+		cookieStores[i] = newFakeStore(i, delay)
+	}
+	return cookieStores
+}
+
+func TestReadCookieStoresKeepsOrderAndCloses(t *testing.T) {
+	cookieStores := fakeStores(20, time.Millisecond)
+	results := readCookieStores(cookieStores, nil, Options{})
+
+	for i, result := range results {
+		if result.err != nil {
+			t.Errorf("store %d: %v", i, result.err)
+		}
+		if want := fmt.Sprintf("c%d", i); len(result.cookies) != 1 || result.cookies[0].Name != want {
+			t.Errorf("store %d: got %v, want cookie %s", i, result.cookies, want)
+		}
+		if !cookieStores[i].(*fakeStore).closed.Load() {
+			t.Errorf("store %d wasn't closed", i)
+		}
+	}
+}
+
+func TestReadCookieStoresTimeoutClosesAfterRead(t *testing.T) {
+	store := newFakeStore(0, 50*time.Millisecond)
+	results := readCookieStores([]kooky.CookieStore{store}, nil, Options{PerStoreTimeout: time.Millisecond})
+	if results[0].err == nil {
+		t.Fatal("expected a timeout error")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !store.closed.Load() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !store.closed.Load() {
+		t.Error("the timed out store wasn't closed after its read")
+	}
+	if store.closedWhileReading.Load() {
+		t.Error("the timed out store was closed while it was still read")
+	}
+}
+
+func TestReadDomainsNewestFirst(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	stores := fakeStores(2, 0)
+	first, second := stores[0].(*fakeStore), stores[1].(*fakeStore)
+	first.cookies[0].Creation = created
+	first.cookies = append(first.cookies, &kooky.Cookie{Cookie: http.Cookie{Name: "a", Domain: "app.example.com"}, Creation: created.Add(time.Hour)})
+	second.cookies[0].Domain = "example.org"
+	second.cookies[0].Creation = created.Add(-time.Hour)
+	second.cookies = append(second.cookies, &kooky.Cookie{Cookie: http.Cookie{Name: "b", Domain: ".example.org"}, Creation: created.Add(2 * time.Hour)})
+
+	domains := readDomains(stores, Options{})
+	want := []string{"example.org", "app.example.com", "example.com"}
+	if fmt.Sprint(domains) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", domains, want)
+	}
+	if !first.closed.Load() || !second.closed.Load() {
+		t.Error("the stores weren't closed")
+	}
+}
+
+// BenchmarkReadCookieStores compares reading the stores one after another with the worker pool
+func BenchmarkReadCookieStores(b *testing.B) {
+	defaultWorkers := storeReadWorkers
+	defer func() { storeReadWorkers = defaultWorkers }()
+
+	for _, workers := range []int{1, max(defaultWorkers, 4)} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			storeReadWorkers = workers
+			for range b.N {
+				readCookieStores(fakeStores(16, 2*time.Millisecond), nil, Options{})
+			}
+		})
+	}
+}
+
+func TestReaderSkipsStoresWithRunningReads(t *testing.T) {
+	store := newFakeStore(0, 100*time.Millisecond)
+	reader := NewReader()
+	reader.stores["all"] = []kooky.CookieStore{store}
+
+	var storeErrors []error
+	opts := Options{Browser: "all", PerStoreTimeout: 10 * time.Millisecond, StoreError: func(err error) {
+		storeErrors = append(storeErrors, err)
+	}}
+
+	if _, err := reader.Fetch(opts); len(storeErrors) != 1 {
+		t.Fatalf("got %v and store errors %v, want the timeout", err, storeErrors)
+	}
+	if _, err := reader.Fetch(opts); !errors.Is(err, ErrStoreRead) || !errors.Is(storeErrors[1], errStillReading) {
+		t.Errorf("got %v and store errors %v while the first read runs, want the store skipped", err, storeErrors)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	opts.PerStoreTimeout = time.Second
+	cookies, err := reader.Fetch(opts)
+	if err != nil || len(cookies) != 1 {
+		t.Errorf("got %v and %v after the first read finished, want the cookie", cookies, err)
+	}
+	if store.closedWhileReading.Load() {
+		t.Error("the store was closed while it was read")
+	}
+}
@@ -0,0 +1,89 @@
+package cookies
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/browserutils/kooky"
+)
+
+// errStillReading is the StoreError of a store whose read of an earlier fetch timed out and is still running
+var errStillReading = errors.New("the read of an earlier fetch timed out and is still running, skipping the store")
+
+// Reader keeps the discovered stores between fetches, for long running programs that read
+// cookies repeatedly. Stores are only discovered once and the Chromium based stores keep the
+// decryption key they got from the keyring, while the store files are still reopened for
+// every fetch, so new cookies are seen. Stores of browsers installed later aren't found.
+type Reader struct {
+	mu sync.Mutex
+	// stores by the argument of FindStores, as Electron apps are discovered separately
+	stores map[string][]kooky.CookieStore
+	// reading are the stores whose timed out reads still run
+	reading storeReads
+}
+
+func NewReader() *Reader {
+	return &Reader{stores: make(map[string][]kooky.CookieStore)}
+}
+
+// Fetch is like the package level Fetch, but reuses the stores of earlier fetches.
+// Fetches are serialized, as a store can't be read concurrently. A store whose read timed out
+// with Options.PerStoreTimeout is skipped, with a StoreError, until that read is finished.
+func (r *Reader) Fetch(opts Options) ([]Cookie, error) {
+	filters, err := opts.Filters()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.StoreFile != "" {
+		return fetchStoreFile(opts, filters)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	discovery := "all"
+	if opts.Browser == "electron" {
+		discovery = "electron"
+	}
+	cookieStores, ok := r.stores[discovery]
+	if !ok {
+		cookieStores = FindStores(discovery, opts.StoreError)
+		r.stores[discovery] = cookieStores
+	}
+
+	selected := opts.SelectStores(cookieStores)
+	ready := make([]kooky.CookieStore, 0, len(selected))
+	for _, store := range selected {
+		if r.reading.running(store) {
+			opts.storeError(newStoreError(store, errStillReading))
+			continue
+		}
+		ready = append(ready, store)
+	}
+	if len(selected) > 0 && len(ready) == 0 {
+		return nil, fmt.Errorf("%w: %w", ErrStoreRead, errStillReading)
+	}
+
+	// reading closes the store files, but leaves the stores usable for the next fetch
+	opts.reading = &r.reading
+	return fetchStores(ready, filters, opts)
+}
+
+// Close closes the files of all stores
+func (r *Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, cookieStores := range r.stores {
+		for _, store := range cookieStores {
+			// a running read closes its store when it's done
+			if !r.reading.running(store) {
+				store.Close()
+			}
+		}
+	}
+	r.stores = make(map[string][]kooky.CookieStore)
+	return nil
+}
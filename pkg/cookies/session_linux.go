@@ -0,0 +1,108 @@
+package cookies
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, which is 100 on every architecture Linux supports in practice
+const clockTicks = 100
+
+// browserProcessNames maps kooky browser names to the process names they run as
+var browserProcessNames = map[string][]string{
+	"chrome":  {"chrome", "google-chrome"},
+	"firefox": {"firefox", "firefox-bin", "firefox-esr"},
+}
+
+// browserStartTime returns the start time of the oldest running process of the given browser,
+// which is the main browser process that was launched by the user
+func browserStartTime(browser string) (time.Time, error) {
+	names, ok := browserProcessNames[browser]
+	if !ok {
+		return time.Time{}, errors.New("process detection is not supported for browser " + browser)
+	}
+
+	bootTime, err := readBootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	statFiles, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var oldest uint64
+	for _, statFile := range statFiles {
+		data, err := os.ReadFile(statFile)
+		if err != nil {
+			// processes can exit while we iterate
+			continue
+		}
+
+		// the process name is enclosed in parentheses and may itself contain spaces
+		stat := string(data)
+		open := strings.IndexByte(stat, '(')
+		closing := strings.LastIndexByte(stat, ')')
+		if open < 0 || closing < open {
+			continue
+		}
+
+		comm := stat[open+1 : closing]
+		if !matchesProcessName(comm, names) {
+			continue
+		}
+
+		// starttime is field 22, the fields after the name start at field 3
+		fields := strings.Fields(stat[closing+1:])
+		if len(fields) < 20 {
+			continue
+		}
+		startTicks, err := strconv.ParseUint(fields[19], 10, 64)
+		if err != nil {
+			continue
+		}
+		if oldest == 0 || startTicks < oldest {
+			oldest = startTicks
+		}
+	}
+
+	if oldest == 0 {
+		return time.Time{}, errors.New("no running " + browser + " process found")
+	}
+
+	return bootTime.Add(time.Duration(oldest) * time.Second / clockTicks), nil
+}
+
+func matchesProcessName(comm string, names []string) bool {
+	for _, name := range names {
+		if comm == name {
+			return true
+		}
+	}
+	return false
+}
+
+func readBootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read boot time: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("failed to parse boot time: %w", err)
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+
+	return time.Time{}, errors.New("boot time not found in /proc/stat")
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package cookies
+
+import (
+	"errors"
+	"runtime"
+	"time"
+)
+
+func browserStartTime(browser string) (time.Time, error) {
+	return time.Time{}, errors.New("browser process detection is not supported on " + runtime.GOOS)
+}
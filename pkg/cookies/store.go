@@ -0,0 +1,302 @@
+package cookies
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/browserutils/kooky"
+	"github.com/browserutils/kooky/browser/chrome"
+	"github.com/browserutils/kooky/browser/firefox"
+	"github.com/browserutils/kooky/browser/safari"
+)
+
+// storeOpeners are the readers that can be forced with Options.StoreType
+var storeOpeners = map[string]func(filename string, filters ...kooky.Filter) (kooky.CookieStore, error){
+	"chrome":  chrome.CookieStore,
+	"firefox": firefox.CookieStore,
+	"safari":  safari.CookieStore,
+}
+
+// StoreTypes lists the values of Options.StoreType
+const StoreTypes = "chrome|firefox|safari"
+
+// BrowserStoreTypes maps the browsers to the reader of their store format
+var BrowserStoreTypes = map[string]string{
+	"chrome":   "chrome",
+	"chromium": "chrome",
+	"edge":     "chrome",
+	"electron": "chrome",
+	"brave":    "chrome",
+	"vivaldi":  "chrome",
+	"opera":    "chrome",
+	"firefox":  "firefox",
+	"safari":   "safari",
+}
+
+// detectStoreType guesses the reader for a store file from the default file names of the browsers
+func detectStoreType(filename string) (string, error) {
+	base := filepath.Base(filename)
+	switch {
+	case base == "Cookies":
+		return "chrome", nil
+	case base == "cookies.sqlite":
+		return "firefox", nil
+	case strings.HasSuffix(base, ".binarycookies"):
+		return "safari", nil
+	}
+
+	return "", fmt.Errorf("can't detect the store type of %s, use one of %s", filename, StoreTypes)
+}
+
+// OpenStoreFile opens a single cookie store file, bypassing the store discovery.
+// The reader is detected from the file name if storeType is empty.
+func OpenStoreFile(filename string, storeType string) (kooky.CookieStore, error) {
+	if _, err := os.Stat(filename); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStoreRead, err)
+	}
+
+	if storeType == "" {
+		detected, err := detectStoreType(filename)
+		if err != nil {
+			return nil, err
+		}
+		storeType = detected
+	}
+
+	opener, ok := storeOpeners[storeType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported store type %s, use one of %s", storeType, StoreTypes)
+	}
+
+	store, err := opener(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s as %s store, try another store type: %w", ErrStoreRead, filename, storeType, err)
+	}
+
+	return store, nil
+}
+
+// FindStores discovers the stores of all browsers, or of the Electron apps for browser "electron".
+// Stores kooky doesn't find itself that can't be opened are passed to storeError, if it isn't nil.
+func FindStores(browser string, storeError func(err error)) []kooky.CookieStore {
+	if browser == "electron" {
+		return findElectronCookieStores(storeError)
+	}
+	return append(kooky.FindAllCookieStores(), findChromiumForkCookieStores(storeError)...)
+}
+
+// SelectStores returns the stores of o.Browser that should be read
+func (o Options) SelectStores(cookieStores []kooky.CookieStore) []kooky.CookieStore {
+	var selected []kooky.CookieStore
+	for _, store := range cookieStores {
+		if !o.readsBrowser(store.Browser()) {
+			continue
+		}
+
+		if len(o.Profiles) > 0 && !o.isProfileIncluded(store) {
+			continue
+		}
+
+		// skipped before reading, so known-bad profiles don't add store errors
+		if o.isProfileExcluded(store.Profile()) {
+			continue
+		}
+
+		selected = append(selected, store)
+	}
+
+	if o.PreferNewestStore {
+		return newestCookieStore(selected)
+	}
+
+	return selected
+}
+
+// readsBrowser reports whether the stores of storeBrowser are read for o.Browser,
+// which can be a single browser, a comma separated list or 'all'
+func (o Options) readsBrowser(storeBrowser string) bool {
+	if o.Browser == "all" || o.Browser == "" {
+		return true
+	}
+	for _, listed := range strings.Split(o.Browser, ",") {
+		if storeBrowser == listed {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Options) isProfileIncluded(store kooky.CookieStore) bool {
+	directory := ProfileDirectory(store)
+	for _, profile := range o.Profiles {
+		if profile == store.Profile() || profile == directory {
+			return true
+		}
+	}
+	return false
+}
+
+// ProfileDirectory returns the name of the profile directory containing the store file,
+// newer Chromium versions keep the store in a Network subdirectory of the profile
+func ProfileDirectory(store kooky.CookieStore) string {
+	dir := filepath.Dir(store.FilePath())
+	if filepath.Base(dir) == "Network" {
+		dir = filepath.Dir(dir)
+	}
+	return filepath.Base(dir)
+}
+
+func (o Options) isProfileExcluded(profile string) bool {
+	for _, excluded := range o.ExcludeProfiles {
+		if profile == excluded {
+			return true
+		}
+	}
+	for _, pattern := range o.ExcludeProfileGlobs {
+		// invalid patterns never match
+		if matched, _ := path.Match(pattern, profile); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// newestCookieStore returns only the store whose file was modified last,
+// which usually belongs to the profile that is currently in use
+func newestCookieStore(cookieStores []kooky.CookieStore) []kooky.CookieStore {
+	var newest kooky.CookieStore
+	var newestModTime time.Time
+	for _, store := range cookieStores {
+		modTime, err := StoreModTime(store)
+		if err != nil {
+			continue
+		}
+		if newest == nil || modTime.After(newestModTime) {
+			newest = store
+			newestModTime = modTime
+		}
+	}
+
+	if newest == nil {
+		return nil
+	}
+
+	return []kooky.CookieStore{newest}
+}
+
+// StoreModTime returns the modification time of the store file
+func StoreModTime(store kooky.CookieStore) (time.Time, error) {
+	info, err := os.Stat(store.FilePath())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+func closeUnselectedStores(cookieStores []kooky.CookieStore, selected []kooky.CookieStore) {
+	isSelected := make(map[kooky.CookieStore]bool, len(selected))
+	for _, store := range selected {
+		isSelected[store] = true
+	}
+	for _, store := range cookieStores {
+		if !isSelected[store] {
+			store.Close()
+		}
+	}
+}
+
+// MostRecentBrowser returns the browser with the most recently modified cookie store,
+// as browsers write their store while they are in use
+func MostRecentBrowser() (string, error) {
+	var recent string
+	var recentModTime time.Time
+	for _, store := range FindStores("all", nil) {
+		defer store.Close()
+
+		modTime, err := StoreModTime(store)
+		if err != nil {
+			continue
+		}
+		if recent == "" || modTime.After(recentModTime) {
+			recent = store.Browser()
+			recentModTime = modTime
+		}
+	}
+
+	if recent == "" {
+		return "", errors.New("no cookie store found")
+	}
+
+	return recent, nil
+}
+
+// StoreListing describes a discovered store
+type StoreListing struct {
+	Browser string `json:"browser"`
+	Profile string `json:"profile"`
+	// Directory is the profile directory, which Options.Profiles accepts as well
+	Directory string `json:"directory"`
+	Path      string `json:"path"`
+	Readable  bool   `json:"readable"`
+	// Locked is set if the lock file of a running browser exists next to the store
+	Locked  bool       `json:"locked"`
+	Default bool       `json:"default"`
+	Mtime   *time.Time `json:"mtime"`
+}
+
+// browserLockFiles are the files running browsers keep in the profile directory (Firefox)
+// or its parent, the user data directory (Chromium). They are removed when the browser exits.
+var browserLockFiles = []string{"lock", "parent.lock", "SingletonLock", "lockfile"}
+
+// isStoreLocked checks for the lock file of a running browser in the directories of the store
+func isStoreLocked(store kooky.CookieStore) bool {
+	dir := filepath.Dir(store.FilePath())
+	if filepath.Base(dir) == "Network" {
+		dir = filepath.Dir(dir)
+	}
+	for _, candidate := range []string{dir, filepath.Dir(dir)} {
+		for _, lockFile := range browserLockFiles {
+			// Lstat, as the lock of Firefox and Chromium on Linux is a dangling symlink
+			if _, err := os.Lstat(filepath.Join(candidate, lockFile)); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ListStores describes all discovered stores without reading any cookies
+func ListStores() []StoreListing {
+	cookieStores := FindStores("all", nil)
+	listings := make([]StoreListing, 0, len(cookieStores))
+
+	for _, store := range cookieStores {
+		defer store.Close()
+
+		listing := StoreListing{
+			Browser:   store.Browser(),
+			Profile:   store.Profile(),
+			Directory: ProfileDirectory(store),
+			Path:      store.FilePath(),
+			Locked:    isStoreLocked(store),
+			Default:   store.IsDefaultProfile(),
+		}
+		if modTime, err := StoreModTime(store); err == nil {
+			modTime = modTime.UTC()
+			listing.Mtime = &modTime
+		}
+		if file, err := os.Open(listing.Path); err == nil {
+			file.Close()
+			listing.Readable = true
+		}
+
+		listings = append(listings, listing)
+	}
+
+	return listings
+}
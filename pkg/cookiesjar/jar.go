@@ -0,0 +1,311 @@
+// Package cookiesjar loads browser cookies into an http.CookieJar for Go HTTP clients:
+//
+//	jar, err := cookiesjar.FromBrowser("firefox", "example.com")
+//	client := &http.Client{Jar: jar}
+//
+// The jar can be saved to disk and loaded again, e.g. from a file exported with --format gojar.
+package cookiesjar
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/What-is-water93/cookies/pkg/cookies"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Entry is a cookie in the file written by Save
+type Entry struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Domain is the domain without a leading dot, HostOnly cookies are only sent to exactly it
+	Domain   string `json:"domain"`
+	HostOnly bool   `json:"hostOnly"`
+	Path     string `json:"path"`
+	// Expires is nil for session cookies
+	Expires  *time.Time `json:"expires"`
+	Secure   bool       `json:"secure"`
+	HttpOnly bool       `json:"httpOnly"`
+}
+
+// Jar is an http.CookieJar that remembers its cookies, so they can be saved.
+// The matching of requests is done by net/http/cookiejar with the public suffix list.
+type Jar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	entries map[string]Entry
+}
+
+// FromBrowser reads the unexpired cookies of browser whose domain contains domain into a new jar
+func FromBrowser(browser string, domain string) (*Jar, error) {
+	found, err := cookies.Fetch(cookies.Options{Browser: browser, Domain: domain})
+	if err != nil {
+		return nil, err
+	}
+	return FromCookies(found)
+}
+
+// FromCookies creates a jar with the cookies read by the cookies package
+func FromCookies(found []cookies.Cookie) (*Jar, error) {
+	entries := make([]Entry, 0, len(found))
+	for _, cookie := range found {
+		entry := Entry{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   strings.TrimPrefix(cookie.Domain, "."),
+			HostOnly: !strings.HasPrefix(cookie.Domain, "."),
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		if !cookie.Expires.IsZero() {
+			expires := cookie.Expires.UTC()
+			entry.Expires = &expires
+		}
+		entries = append(entries, entry)
+	}
+	return New(entries)
+}
+
+// Load reads a jar written by Save
+func Load(filename string) (*Jar, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid cookie jar %s: %w", filename, err)
+	}
+	return New(entries)
+}
+
+// New creates a jar with the entries, expired ones are dropped
+func New(entries []Entry) (*Jar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Jar{jar: jar, entries: make(map[string]Entry, len(entries))}
+	for _, entry := range entries {
+		j.setEntry(entry)
+	}
+	return j, nil
+}
+
+// recordEntry stores the entry, or removes the cookie it replaces if it's expired.
+// It reports if the entry is unexpired.
+func (j *Jar) recordEntry(entry Entry) bool {
+	if entry.Expires != nil && !entry.Expires.After(time.Now()) {
+		delete(j.entries, entryKey(entry))
+		return false
+	}
+	j.entries[entryKey(entry)] = entry
+	return true
+}
+
+// setEntry stores the entry and passes it to the cookiejar as if it was set by a response of its domain
+func (j *Jar) setEntry(entry Entry) {
+	if entry.Path == "" {
+		entry.Path = "/"
+	}
+	if !j.recordEntry(entry) {
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     entry.Name,
+		Value:    entry.Value,
+		Path:     entry.Path,
+		Secure:   entry.Secure,
+		HttpOnly: entry.HttpOnly,
+	}
+	// a cookie without Domain attribute is a host-only cookie for the cookiejar
+	if !entry.HostOnly {
+		cookie.Domain = entry.Domain
+	}
+	if entry.Expires != nil {
+		cookie.Expires = *entry.Expires
+	}
+
+	scheme := "http"
+	if entry.Secure {
+		scheme = "https"
+	}
+	j.jar.SetCookies(&url.URL{Scheme: scheme, Host: entry.Domain, Path: entry.Path}, []*http.Cookie{cookie})
+}
+
+func entryKey(entry Entry) string {
+	return entry.Domain + "\x00" + entry.Path + "\x00" + entry.Name
+}
+
+// SetCookies implements http.CookieJar, the cookies set by responses are saved as well.
+// Cookies the cookiejar rejects for u, e.g. for the domain of another site, aren't saved.
+func (j *Jar) SetCookies(u *url.URL, responseCookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return
+	}
+
+	accepted := make([]*http.Cookie, 0, len(responseCookies))
+	for _, cookie := range responseCookies {
+		domain, hostOnly, ok := cookieDomain(host, cookie.Domain)
+		if !ok {
+			continue
+		}
+		accepted = append(accepted, cookie)
+
+		entry := Entry{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   domain,
+			HostOnly: hostOnly,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		if entry.Path == "" || !strings.HasPrefix(entry.Path, "/") {
+			entry.Path = defaultPath(u)
+		}
+		switch {
+		case cookie.MaxAge < 0:
+			expired := time.Unix(0, 0).UTC()
+			entry.Expires = &expired
+		case cookie.MaxAge > 0:
+			expires := time.Now().Add(time.Duration(cookie.MaxAge) * time.Second).UTC()
+			entry.Expires = &expires
+		case !cookie.Expires.IsZero():
+			expires := cookie.Expires.UTC()
+			entry.Expires = &expires
+		}
+		j.recordEntry(entry)
+	}
+	j.jar.SetCookies(u, accepted)
+}
+
+// canonicalHost is the lower case host of a request without port, the way the cookiejar matches it
+func canonicalHost(host string) (string, error) {
+	if hasPort(host) {
+		var err error
+		host, _, err = net.SplitHostPort(host)
+		if err != nil {
+			return "", err
+		}
+	}
+	host = strings.TrimSuffix(host, ".")
+	return strings.ToLower(host), nil
+}
+
+func hasPort(host string) bool {
+	colons := strings.Count(host, ":")
+	if colons == 0 {
+		return false
+	}
+	if colons == 1 {
+		return true
+	}
+	return host[0] == '[' && strings.Contains(host, "]:")
+}
+
+// cookieDomain returns the domain a response from host may set a cookie for, following the
+// rules of the cookiejar (RFC 6265 5.3): the Domain attribute must be host or one of its
+// parents and must not be a public suffix. ok is false if the cookiejar rejects the cookie.
+func cookieDomain(host string, domain string) (string, bool, bool) {
+	if domain == "" {
+		return host, true, true
+	}
+	if net.ParseIP(host) != nil {
+		return host, true, host == domain
+	}
+
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if domain == "" || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", false, false
+	}
+	if suffix, _ := publicsuffix.PublicSuffix(domain); suffix != "" && !strings.HasSuffix(domain, "."+suffix) {
+		// a cookie for a public suffix is only kept as host-only cookie of the suffix itself
+		return host, true, host == domain
+	}
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return "", false, false
+	}
+	return domain, false, true
+}
+
+// defaultPath is the directory of the request path, the path of cookies without a Path attribute (RFC 6265 5.1.4)
+func defaultPath(u *url.URL) string {
+	i := strings.LastIndex(u.Path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return u.Path[:i]
+}
+
+// Cookies implements http.CookieJar
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Entries returns the unexpired cookies of the jar, ordered by domain, path and name
+func (j *Jar) Entries() []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries := make([]Entry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		if entry.Expires == nil || entry.Expires.After(time.Now()) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entryKey(entries[a]) < entryKey(entries[b])
+	})
+	return entries
+}
+
+// Write writes the cookies of the jar as JSON, in the format read by Load
+func (j *Jar) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(j.Entries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Save writes the jar to filename with 0600 permissions, replacing it atomically
+func (j *Jar) Save(filename string) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := j.Write(file); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
@@ -0,0 +1,89 @@
+package cookiesjar
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func mustParse(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestSetCookiesRejectsOtherDomain(t *testing.T) {
+	jar, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jar.SetCookies(mustParse(t, "https://evil.test/login"), []*http.Cookie{
+		{Name: "session", Value: "planted", Domain: "bank.test", Path: "/"},
+		{Name: "session", Value: "planted", Domain: "com"},
+	})
+
+	if entries := jar.Entries(); len(entries) != 0 {
+		t.Errorf("Entries() = %v, want none", entries)
+	}
+	if cookies := jar.Cookies(mustParse(t, "https://bank.test/")); len(cookies) != 0 {
+		t.Errorf("Cookies(bank.test) = %v, want none", cookies)
+	}
+}
+
+func TestSetCookiesKeepsSameSite(t *testing.T) {
+	jar, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jar.SetCookies(mustParse(t, "https://www.example.com:8443/account/login"), []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "parent", Value: "2", Domain: ".example.com", Path: "/"},
+	})
+
+	want := []Entry{
+		{Name: "parent", Value: "2", Domain: "example.com", Path: "/"},
+		{Name: "host", Value: "1", Domain: "www.example.com", HostOnly: true, Path: "/account"},
+	}
+	entries := jar.Entries()
+	if len(entries) != len(want) {
+		t.Fatalf("Entries() = %v, want %v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("Entries()[%d] = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	if cookies := jar.Cookies(mustParse(t, "https://www.example.com/account/settings")); len(cookies) != 2 {
+		t.Errorf("Cookies(www.example.com/account) = %v, want host and parent", cookies)
+	}
+	if cookies := jar.Cookies(mustParse(t, "https://api.example.com/")); len(cookies) != 1 || cookies[0].Name != "parent" {
+		t.Errorf("Cookies(api.example.com) = %v, want parent", cookies)
+	}
+}
+
+func TestSetCookiesDeletesOnlyOwnCookies(t *testing.T) {
+	jar, err := New([]Entry{{Name: "session", Value: "1", Domain: "bank.test", Path: "/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jar.SetCookies(mustParse(t, "https://evil.test/"), []*http.Cookie{
+		{Name: "session", Domain: "bank.test", Path: "/", MaxAge: -1},
+	})
+	if entries := jar.Entries(); len(entries) != 1 {
+		t.Errorf("Entries() = %v, want the session cookie of bank.test", entries)
+	}
+
+	jar.SetCookies(mustParse(t, "https://bank.test/"), []*http.Cookie{
+		{Name: "session", Domain: "bank.test", Path: "/", MaxAge: -1},
+	})
+	if entries := jar.Entries(); len(entries) != 0 {
+		t.Errorf("Entries() = %v, want none after the deletion", entries)
+	}
+}
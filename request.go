@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+	"github.com/browserutils/kooky"
+)
+
+// requestCommands are the values of --command
+var requestCommands = []string{"curl", "wget", "httpie", "powershell"}
+
+// createRequestCommand prints a command line for the tool that requests the target URL with the cookies.
+// Every argument is quoted, so values with quotes or spaces can be pasted into the shell as they are.
+func createRequestCommand(tool string, cookies []*kooky.Cookie) string {
+	if targetURL != "" {
+		cookies = cookiesForURL(cookies, targetURL)
+	}
+	header := cookielib.CookieHeader(sourcedCookies(cookies))
+	for _, cookie := range cookies {
+		// no quoting helps here, a ; ends the cookie in the Cookie header itself
+		if strings.Contains(cookie.Value, ";") {
+			logWarning("the value of cookie %q contains a ';', the server will only see the part before it", cookie.Name)
+		}
+	}
+	target := requestURL(cookies)
+
+	switch tool {
+	case "wget":
+		return fmt.Sprintf("wget --header=%s %s", shellQuote("Cookie: "+header), shellQuote(target))
+	case "httpie":
+		return fmt.Sprintf("http %s %s", shellQuote(target), shellQuote("Cookie:"+header))
+	case "powershell":
+		return fmt.Sprintf("Invoke-WebRequest -Uri %s -Headers @{ 'Cookie' = %s }", powershellQuote(target), powershellQuote(header))
+	}
+	return fmt.Sprintf("curl -H %s %s", shellQuote("Cookie: "+header), shellQuote(target))
+}
+
+// cookiesForURL keeps the cookies a browser would send to rawURL: the host must be the domain
+// of a host-only cookie or within the domain of a domain cookie, the path must be within the
+// cookie path and Secure cookies are only sent over https (RFC 6265 5.4).
+func cookiesForURL(cookies []*kooky.Cookie, rawURL string) []*kooky.Cookie {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return cookies
+	}
+	host := strings.ToLower(target.Hostname())
+	requestPath := target.EscapedPath()
+	if requestPath == "" {
+		requestPath = "/"
+	}
+
+	sent := make([]*kooky.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		cookieDomain := strings.ToLower(cookie.Domain)
+		hostMatches := host == strings.TrimPrefix(cookieDomain, ".")
+		// a leading dot marks a domain cookie, which is sent to the subdomains as well
+		if strings.HasPrefix(cookieDomain, ".") && strings.HasSuffix(host, cookieDomain) {
+			hostMatches = true
+		}
+		if !hostMatches || !pathMatches(requestPath, cookie.Path) || (cookie.Secure && target.Scheme != "https") {
+			continue
+		}
+		sent = append(sent, cookie)
+	}
+
+	if len(sent) == 0 && len(cookies) > 0 {
+		logWarning("none of the %d matching cookies is sent to %s", len(cookies), rawURL)
+	} else if len(sent) < len(cookies) {
+		logDiagnostic("info", fmt.Sprintf("%d of %d matching cookies aren't sent to %s and are left out", len(cookies)-len(sent), len(cookies), rawURL), "")
+	}
+	return sent
+}
+
+// pathMatches reports whether a cookie with cookiePath is sent to requestPath (RFC 6265 5.1.4)
+func pathMatches(requestPath string, cookiePath string) bool {
+	if cookiePath == "" || cookiePath == requestPath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// requestURL is --url if given, otherwise the domain with the scheme of requestScheme
+func requestURL(cookies []*kooky.Cookie) string {
+	if targetURL != "" {
+		return targetURL
+	}
+	return requestScheme(cookies) + "://" + domain
+}
+
+// shellQuote quotes s for POSIX shells, a ' ends the quoted string, is escaped and starts a new one
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powershellQuote quotes s as a PowerShell string literal, which escapes ' by doubling it
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
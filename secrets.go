@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/browserutils/kooky"
+)
+
+// secretFileName maps a cookie name to a file name that can't escape the secrets directory
+func secretFileName(cookieName string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, cookieName)
+
+	if strings.Trim(sanitized, ".") == "" {
+		sanitized = strings.Repeat("_", len(sanitized)+1)
+	}
+
+	return sanitized
+}
+
+// writeSecretsDir writes every cookie value into its own file, as used for docker and kubernetes secrets
+func writeSecretsDir(dir string, cookies []*kooky.Cookie) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// the cookies sharing a name share the file, the one read last wins
+	valueByFile := make(map[string]string, len(cookies))
+	nameByFile := make(map[string]string, len(cookies))
+	for _, cookie := range cookies {
+		fileName := secretFileName(cookieKey(cookie))
+		if previous, ok := nameByFile[fileName]; ok {
+			logWarning("cookies %q and %q both map to %s, keeping %q", previous, cookie.Name, fileName, cookie.Name)
+		}
+		nameByFile[fileName] = cookie.Name
+		valueByFile[fileName] = cookie.Value
+	}
+
+	for fileName, value := range valueByFile {
+		if err := replaceFile(filepath.Join(dir, fileName), value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
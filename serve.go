@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+)
+
+// servedCookie is a cookie in the responses of the HTTP API
+type servedCookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Domain   string     `json:"domain"`
+	Path     string     `json:"path"`
+	Expires  *time.Time `json:"expires"`
+	Secure   bool       `json:"secure"`
+	HttpOnly bool       `json:"httpOnly"`
+	Browser  string     `json:"browser"`
+	Profile  string     `json:"profile"`
+}
+
+// serve answers GET /cookies and GET /stores until the server fails.
+// The stores are discovered on the first request and kept for all later ones.
+func serve(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %s: %w", address, err)
+	}
+	loopback := isLoopbackHost(host)
+	if !loopback {
+		logWarning("%s is reachable from other machines, anyone who can connect can read your cookies", address)
+	}
+
+	reader := cookielib.NewReader()
+	defer reader.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cookies", func(w http.ResponseWriter, r *http.Request) {
+		serveCookies(w, r, reader)
+	})
+	mux.HandleFunc("/stores", func(w http.ResponseWriter, r *http.Request) {
+		writeJsonResponse(w, http.StatusOK, cookielib.ListStores())
+	})
+
+	handler := http.Handler(mux)
+	handler = onlyGet(handler)
+	if loopback {
+		handler = onlyLoopbackHosts(handler)
+	}
+
+	logDiagnostic("info", "listening on http://"+address, "")
+	return http.ListenAndServe(address, handler)
+}
+
+func serveCookies(w http.ResponseWriter, r *http.Request, reader *cookielib.Reader) {
+	query := r.URL.Query()
+
+	requestBrowser := query.Get("browser")
+	if requestBrowser == "" {
+		requestBrowser = browser
+	}
+	if requestBrowser == "recent" || !isSupportedBrowser(requestBrowser) {
+		writeJsonError(w, http.StatusBadRequest, fmt.Errorf("unsupported browser: %s", requestBrowser))
+		return
+	}
+
+	opts := fetchOptions(requestBrowser, resolveDomainAlias(query.Get("domain")))
+	if opts.Domain == "" {
+		writeJsonError(w, http.StatusBadRequest, errors.New("parameter domain is required"))
+		return
+	}
+	if query.Has("path") {
+		opts.Path = query.Get("path")
+	}
+	if query.Has("match") {
+		match := query.Get("match")
+		switch match {
+		case "contains", "exact", "suffix", "regex":
+		default:
+			writeJsonError(w, http.StatusBadRequest, fmt.Errorf("invalid value for parameter match: %s, use one of contains, exact, suffix or regex", match))
+			return
+		}
+		opts.ExactDomain, opts.SuffixDomain, opts.RegexDomain = match == "exact", match == "suffix", match == "regex"
+	}
+	for param, option := range map[string]*bool{
+		"exact-domain": &opts.ExactDomain,
+		"expired":      &opts.IncludeExpired,
+		"secure-only":  &opts.SecureOnly,
+		"http-only":    &opts.HttpOnly,
+	} {
+		if !query.Has(param) {
+			continue
+		}
+		value, err := strconv.ParseBool(query.Get(param))
+		if err != nil {
+			writeJsonError(w, http.StatusBadRequest, fmt.Errorf("invalid value for parameter %s: %w", param, err))
+			return
+		}
+		*option = value
+	}
+
+	// the options come from the request, so an invalid domain pattern is the client's fault
+	if _, err := opts.Filters(); err != nil {
+		writeJsonError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// the server runs for a long time, collecting the store errors like a single run would leak them
+	opts.StoreError = func(err error) {
+		reportStoreError(err)
+	}
+
+	fetched, err := reader.Fetch(opts)
+	if err != nil && !errors.Is(err, errNoCookies) {
+		writeJsonError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	wanted := make(map[string]bool, len(query["name"]))
+	for _, name := range query["name"] {
+		wanted[name] = true
+	}
+
+	served := make([]servedCookie, 0, len(fetched))
+	for _, cookie := range fetched {
+		if len(wanted) > 0 && !wanted[normalizeName(cookie.Name)] {
+			continue
+		}
+		served = append(served, newServedCookie(cookie))
+	}
+	sort.SliceStable(served, func(i, j int) bool {
+		if served[i].Name != served[j].Name {
+			return served[i].Name < served[j].Name
+		}
+		if served[i].Domain != served[j].Domain {
+			return served[i].Domain < served[j].Domain
+		}
+		return served[i].Path < served[j].Path
+	})
+
+	writeJsonResponse(w, http.StatusOK, served)
+}
+
+func newServedCookie(cookie cookielib.Cookie) servedCookie {
+	served := servedCookie{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   cookie.Domain,
+		Path:     cookie.Path,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		Browser:  cookie.Browser,
+		Profile:  cookie.Profile,
+	}
+	if !cookielib.IsSession(cookie.Cookie) {
+		expires := cookie.Expires.UTC()
+		served.Expires = &expires
+	}
+	return served
+}
+
+func writeJsonResponse(w http.ResponseWriter, status int, v interface{}) {
+	body, err := marshalJson(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+func writeJsonError(w http.ResponseWriter, status int, err error) {
+	writeJsonResponse(w, status, map[string]string{"error": err.Error()})
+}
+
+func onlyGet(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeJsonError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// onlyLoopbackHosts rejects requests for other host names, otherwise a web page could
+// read the cookies by rebinding its own domain to 127.0.0.1
+func onlyLoopbackHosts(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !isLoopbackHost(host) {
+			writeJsonError(w, http.StatusForbidden, fmt.Errorf("host %s not allowed", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
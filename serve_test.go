@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+)
+
+func TestServeCookiesRejectsInvalidParameters(t *testing.T) {
+	resetFlags(t)
+	reader := cookielib.NewReader()
+	defer reader.Close()
+
+	for _, query := range []string{
+		"",
+		"domain=example.com&browser=netscape",
+		"domain=example.com&match=glob",
+		"domain=(example&match=regex",
+		"domain=example.com&secure-only=maybe",
+	} {
+		recorder := httptest.NewRecorder()
+		serveCookies(recorder, httptest.NewRequest(http.MethodGet, "/cookies?"+query, nil), reader)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%q: got status %d, want %d: %s", query, recorder.Code, http.StatusBadRequest, recorder.Body)
+		}
+	}
+}
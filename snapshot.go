@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// snapshotFormat names the encryption of the snapshot, so files of later versions are recognized
+const snapshotFormat = "cookies-snapshot/aes-256-gcm+scrypt"
+
+// scrypt parameters recommended for interactive use in 2017, a snapshot is decrypted rarely
+const (
+	snapshotScryptN = 1 << 15
+	snapshotScryptR = 8
+	snapshotScryptP = 1
+)
+
+// snapshotMaxScryptMemory bounds the 128*N*r bytes scrypt allocates for the parameters of a file
+const snapshotMaxScryptMemory = 256 << 20
+
+// encryptedSnapshot is a --full output encrypted with a key derived from the passphrase.
+// The byte slices are base64 encoded by encoding/json.
+type encryptedSnapshot struct {
+	Format     string `json:"format"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// snapshotPassphrase reads the passphrase from --passphrase-file or $COOKIES_PASSPHRASE,
+// it's never taken from a flag as it would show up in the process list
+func snapshotPassphrase() ([]byte, error) {
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		passphrase := bytes.TrimRight(data, "\r\n")
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("passphrase file %s is empty", passphraseFile)
+		}
+		return passphrase, nil
+	}
+	if passphrase := os.Getenv("COOKIES_PASSPHRASE"); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	return nil, errors.New("the snapshot is encrypted with a passphrase, give it with --passphrase-file or $COOKIES_PASSPHRASE")
+}
+
+func snapshotCipher(passphrase []byte, salt []byte, n int, r int, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSnapshot encrypts the --full output of a snapshot
+func sealSnapshot(output string) (string, error) {
+	passphrase, err := snapshotPassphrase()
+	if err != nil {
+		return "", err
+	}
+
+	sealed := encryptedSnapshot{Format: snapshotFormat, N: snapshotScryptN, R: snapshotScryptR, P: snapshotScryptP, Salt: make([]byte, 16)}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return "", err
+	}
+	aead, err := snapshotCipher(passphrase, sealed.Salt, sealed.N, sealed.R, sealed.P)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt snapshot: %w", err)
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return "", err
+	}
+	// the format is authenticated as well, so it can't be swapped for weaker parameters unnoticed
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, []byte(output), []byte(sealed.Format))
+
+	sealedJson, err := json.Marshal(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(sealedJson), nil
+}
+
+// checkScryptParameters rejects parameters read from a file that would make scrypt take all
+// memory or run for hours, before scrypt allocates anything
+func checkScryptParameters(n int, r int, p int) error {
+	if n < 2 || n&(n-1) != 0 {
+		return fmt.Errorf("invalid scrypt parameter N %d, it must be a power of two", n)
+	}
+	if r < 1 || p < 1 {
+		return fmt.Errorf("invalid scrypt parameters r %d and p %d", r, p)
+	}
+	if r > 64 || p > 64 || r*p > 64 || uint64(n)*uint64(r) > snapshotMaxScryptMemory/128 {
+		return fmt.Errorf("scrypt parameters N %d, r %d and p %d are too expensive", n, r, p)
+	}
+	return nil
+}
+
+// openSnapshot decrypts data if it's an encrypted snapshot, other files are returned as they are
+func openSnapshot(data []byte) ([]byte, error) {
+	var sealed encryptedSnapshot
+	if json.Unmarshal(data, &sealed) != nil || sealed.Format == "" {
+		return data, nil
+	}
+	if sealed.Format != snapshotFormat {
+		return nil, fmt.Errorf("unsupported snapshot format %q", sealed.Format)
+	}
+	if err := checkScryptParameters(sealed.N, sealed.R, sealed.P); err != nil {
+		return nil, fmt.Errorf("failed to decrypt snapshot: %w", err)
+	}
+
+	passphrase, err := snapshotPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := snapshotCipher(passphrase, sealed.Salt, sealed.N, sealed.R, sealed.P)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt snapshot: %w", err)
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, errors.New("failed to decrypt snapshot: invalid nonce")
+	}
+	opened, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(sealed.Format))
+	if err != nil {
+		return nil, errors.New("failed to decrypt snapshot, the passphrase is wrong or the file was modified")
+	}
+	return opened, nil
+}
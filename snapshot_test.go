@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	resetFlags(t)
+	t.Setenv("COOKIES_PASSPHRASE", "hunter2")
+
+	sealed, err := sealSnapshot(`[{"Cookie":{"Name":"sid"}}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := openSnapshot([]byte(sealed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(opened) != `[{"Cookie":{"Name":"sid"}}]` {
+		t.Errorf("got %s", opened)
+	}
+
+	t.Setenv("COOKIES_PASSPHRASE", "wrong")
+	if _, err := openSnapshot([]byte(sealed)); err == nil {
+		t.Error("the snapshot was opened with a wrong passphrase")
+	}
+}
+
+func TestOpenSnapshotRejectsHostileParameters(t *testing.T) {
+	resetFlags(t)
+	t.Setenv("COOKIES_PASSPHRASE", "hunter2")
+
+	tests := []struct {
+		name    string
+		n, r, p int
+		want    string
+	}{
+		// 128*N*r would be 8 GiB
+		{"memory", 1 << 20, 64, 1, "too expensive"},
+		{"huge N", 1 << 30, 1, 1, "too expensive"},
+		{"huge r", 1 << 10, 1 << 30, 1, "too expensive"},
+		{"cpu", 1 << 10, 8, 64, "too expensive"},
+		{"N not a power of two", 3 << 10, 8, 1, "power of two"},
+		{"zero r", 1 << 10, 0, 1, "invalid"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header, err := json.Marshal(encryptedSnapshot{Format: snapshotFormat, N: test.n, R: test.r, P: test.p, Salt: make([]byte, 16), Nonce: make([]byte, 12)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = openSnapshot(header)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"text/template"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+	"github.com/browserutils/kooky"
+)
+
+// templateCookie is the data of --template: the fields of the cookie (Name, Value, Domain, Path,
+// Expires, Secure, HttpOnly, Creation, Container, ...), its store (Browser, Profile, FilePath) and Session
+type templateCookie struct {
+	cookielib.Cookie
+	Session bool
+}
+
+var templateFuncs = template.FuncMap{
+	"shellquote": shellQuote,
+}
+
+func parseOutputTemplate(text string) (*template.Template, error) {
+	return template.New("output").Funcs(templateFuncs).Parse(text)
+}
+
+// executeOutputTemplate runs the template once per cookie, each on its own line,
+// or with --template-all once with the list of all cookies
+func executeOutputTemplate(tmpl *template.Template, cookies []*kooky.Cookie) (string, error) {
+	data := make([]templateCookie, 0, len(cookies))
+	for _, cookie := range sourcedCookies(sortedCookies(cookies)) {
+		data = append(data, templateCookie{Cookie: cookie, Session: cookielib.IsSession(cookie.Cookie)})
+	}
+
+	var b strings.Builder
+	if templateAll {
+		if err := tmpl.Execute(&b, data); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+
+	for _, cookie := range data {
+		if err := tmpl.Execute(&b, cookie); err != nil {
+			return "", err
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+func makeRaw(file *os.File) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on " + runtime.GOOS)
+}
+
+func terminalSize(file *os.File) (int, int) {
+	return 80, 24
+}
+
+func notifyResize(resized chan<- os.Signal) func() {
+	return func() {}
+}
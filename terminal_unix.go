@@ -0,0 +1,54 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/unix"
+)
+
+// makeRaw puts the terminal into raw mode, so every key press is read right away without echo.
+// The returned function restores the previous mode.
+func makeRaw(file *os.File) (func(), error) {
+	fd := int(file.Fd())
+	previous, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	// the same flags as cfmakeraw(3)
+	raw := *previous
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlWriteTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		unix.IoctlSetTermios(fd, ioctlWriteTermios, previous)
+	}, nil
+}
+
+// terminalSize returns the columns and rows of the terminal, 80x24 if they can't be read
+func terminalSize(file *os.File) (int, int) {
+	size, err := unix.IoctlGetWinsize(int(file.Fd()), unix.TIOCGWINSZ)
+	if err != nil || size.Col == 0 || size.Row == 0 {
+		return 80, 24
+	}
+	return int(size.Col), int(size.Row)
+}
+
+// notifyResize sends to resized whenever the terminal changes its size
+func notifyResize(resized chan<- os.Signal) func() {
+	signal.Notify(resized, unix.SIGWINCH)
+	return func() {
+		signal.Stop(resized)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// makeRaw switches the console to raw input with VT escape sequences, like the terminals of
+// other platforms. The returned function restores the previous modes.
+func makeRaw(file *os.File) (func(), error) {
+	input := windows.Handle(file.Fd())
+	var previousInput uint32
+	if err := windows.GetConsoleMode(input, &previousInput); err != nil {
+		return nil, err
+	}
+	raw := previousInput &^ (windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT)
+	if err := windows.SetConsoleMode(input, raw|windows.ENABLE_VIRTUAL_TERMINAL_INPUT); err != nil {
+		return nil, err
+	}
+
+	// the screen is drawn on stderr
+	output := windows.Handle(os.Stderr.Fd())
+	var previousOutput uint32
+	if err := windows.GetConsoleMode(output, &previousOutput); err == nil {
+		windows.SetConsoleMode(output, previousOutput|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	}
+
+	return func() {
+		windows.SetConsoleMode(input, previousInput)
+		windows.SetConsoleMode(output, previousOutput)
+	}, nil
+}
+
+// terminalSize returns the columns and rows of the console window, 80x24 if they can't be read
+func terminalSize(file *os.File) (int, int) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(file.Fd()), &info); err != nil {
+		return 80, 24
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1
+}
+
+// notifyResize does nothing, a resized console is picked up with the next key press
+func notifyResize(resized chan<- os.Signal) func() {
+	return func() {}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	cookielib "github.com/What-is-water93/cookies/pkg/cookies"
+)
+
+// watchEvent is a JSON line of --watch. Old is the cookie before a change or removal.
+type watchEvent struct {
+	Time   time.Time     `json:"time"`
+	Event  string        `json:"event"`
+	Cookie *servedCookie `json:"cookie,omitempty"`
+	Old    *servedCookie `json:"old,omitempty"`
+}
+
+// watchCookies reads the stores every watchInterval and prints an event for every matching
+// cookie that was added, changed or removed since the previous read. The cookies found by the
+// first read are the baseline and aren't reported. It runs until it's interrupted.
+func watchCookies(browser string, domain string) error {
+	reader := cookielib.NewReader()
+	defer reader.Close()
+
+	opts := fetchOptions(browser, domain)
+	// the watch runs for a long time, collecting the store errors like a single run would leak them.
+	// Decryption failures are only warned about once, they would repeat on every poll.
+	baseline := true
+	opts.StoreError = func(err error) {
+		if debug || (baseline && (isDecryptionError(err) || errors.Is(err, cookielib.ErrKeyring))) {
+			reportStoreError(err)
+		}
+	}
+
+	previous, err := fetchWatchedCookies(reader, opts)
+	if err != nil {
+		return err
+	}
+	baseline = false
+	logDiagnostic("info", fmt.Sprintf("watching %d cookies, polling every %s", len(previous), watchInterval), "")
+
+	output := os.Stdout
+	if outputFile != "" {
+		file, err := openOutputFile()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	for {
+		time.Sleep(watchInterval)
+
+		current, err := fetchWatchedCookies(reader, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range diffWatchedCookies(previous, current) {
+			if redact {
+				event = redactWatchEvent(event)
+			}
+			if err := encoder.Encode(event); err != nil {
+				return fmt.Errorf("failed to write event: %w", err)
+			}
+		}
+		previous = current
+	}
+}
+
+// fetchWatchedCookies reads the matching cookies keyed by their store and identity,
+// so a cookie with the same name on another path or in another profile is a different cookie
+func fetchWatchedCookies(reader *cookielib.Reader, opts cookielib.Options) (map[string]servedCookie, error) {
+	fetched, err := reader.Fetch(opts)
+	if err != nil && !errors.Is(err, errNoCookies) {
+		return nil, fmt.Errorf("failed to obtain cookies: %w", err)
+	}
+
+	cookies := make(map[string]servedCookie, len(fetched))
+	for _, cookie := range fetched {
+		key := cookie.FilePath + "\x00" + cookie.Domain + "\x00" + cookie.Path + "\x00" + cookie.Name
+		// the values stay raw for the comparison, redacted values of the same length look the same
+		cookies[key] = newServedCookie(cookie)
+	}
+	return cookies, nil
+}
+
+// redactWatchEvent redacts the values of the event for printing with --redact
+func redactWatchEvent(event watchEvent) watchEvent {
+	if event.Cookie != nil {
+		redacted := *event.Cookie
+		redacted.Value = redactValue(redacted.Value)
+		event.Cookie = &redacted
+	}
+	if event.Old != nil {
+		redacted := *event.Old
+		redacted.Value = redactValue(redacted.Value)
+		event.Old = &redacted
+	}
+	return event
+}
+
+// diffWatchedCookies returns the events turning previous into current, ordered by cookie
+func diffWatchedCookies(previous map[string]servedCookie, current map[string]servedCookie) []watchEvent {
+	now := time.Now().UTC()
+
+	var keys []string
+	for key := range current {
+		keys = append(keys, key)
+	}
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var events []watchEvent
+	for _, key := range keys {
+		newCookie, isCurrent := current[key]
+		oldCookie, wasPrevious := previous[key]
+		switch {
+		case !wasPrevious:
+			events = append(events, watchEvent{Time: now, Event: "added", Cookie: &newCookie})
+		case !isCurrent:
+			events = append(events, watchEvent{Time: now, Event: "removed", Old: &oldCookie})
+		case !sameServedCookie(oldCookie, newCookie):
+			events = append(events, watchEvent{Time: now, Event: "changed", Cookie: &newCookie, Old: &oldCookie})
+		}
+	}
+	return events
+}
+
+func sameServedCookie(a servedCookie, b servedCookie) bool {
+	if (a.Expires == nil) != (b.Expires == nil) || (a.Expires != nil && !a.Expires.Equal(*b.Expires)) {
+		return false
+	}
+	return a.Value == b.Value && a.Secure == b.Secure && a.HttpOnly == b.HttpOnly
+}